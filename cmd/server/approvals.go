@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"ai-presence-mcp/internal/approval"
+)
+
+// RunApprovalCommand lists pending actions or approves/denies one by token.
+// Output goes to stdout since this runs as a standalone CLI command.
+func RunApprovalCommand(list bool, approveToken, denyToken string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	manager := approval.NewManager(cfg.Approval)
+
+	switch {
+	case approveToken != "":
+		action, err := manager.Approve(approveToken, cliActor())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Approved %s (%s). The server will execute it shortly.\n", action.Token, action.Tool)
+	case denyToken != "":
+		action, err := manager.Deny(denyToken, cliActor())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Denied %s (%s).\n", action.Token, action.Tool)
+	case list:
+		actions, err := manager.List(approval.StatusPending)
+		if err != nil {
+			return err
+		}
+		if len(actions) == 0 {
+			fmt.Println("No actions are awaiting approval.")
+			return nil
+		}
+		for _, a := range actions {
+			fmt.Printf("Token: %s\nTool: %s\nExpires: %s\n\n%s\n\n---\n\n",
+				a.Token, a.Tool, a.ExpiresAt.Local().Format(time.RFC1123), a.Preview)
+		}
+	}
+
+	return nil
+}
+
+func cliActor() string {
+	name := "cli"
+	if u, err := user.Current(); err == nil {
+		name = "cli:" + u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	return name
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"ai-presence-mcp/internal/config"
+	"ai-presence-mcp/internal/discovery"
+)
+
+// RunDiscover looks up the mail servers of address, refreshing the discovery
+// cache, and prints them as an account block for config.yaml. Output goes to
+// stdout since this runs as a standalone CLI command.
+func RunDiscover(address string) error {
+	cfg, err := config.LoadDiscoveryConfig(configPath())
+	if errors.Is(err, os.ErrNotExist) && os.Getenv("CONFIG_PATH") == "" {
+		cfg, err = config.LoadDiscoveryConfig("")
+	}
+	if err != nil {
+		return err
+	}
+
+	settings, err := discovery.New(cfg).Discover(context.Background(), address)
+	if err != nil {
+		return err
+	}
+
+	// Servers outside the address's domain are only printed for a human to
+	// check, never cached where Load would apply them
+	if cfg.CachePath != "" && !settings.Unverified {
+		cache, err := discovery.OpenCache(cfg.CachePath)
+		if err == nil {
+			err = cache.Put(address, settings)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	fmt.Printf("# Found in %s\n", settings.Source)
+	if settings.Unverified {
+		fmt.Printf("# WARNING: these servers are outside the address's domain and DNS is\n")
+		fmt.Printf("# unauthenticated. Check they belong to your provider before using them.\n")
+	}
+	fmt.Printf("email:\n")
+	fmt.Printf("  - username: %q\n", address)
+	fmt.Printf("    password: \"${MAIL_PASSWORD}\"\n")
+	fmt.Printf("    imap_server: %q\n", settings.IMAPServer)
+	fmt.Printf("    imap_port: %d\n", settings.IMAPPort)
+	fmt.Printf("    smtp_server: %q\n", settings.SMTPServer)
+	fmt.Printf("    smtp_port: %d\n", settings.SMTPPort)
+	fmt.Printf("    tls:\n")
+	fmt.Printf("      imap_mode: %q\n", settings.IMAPTLS)
+	fmt.Printf("      smtp_mode: %q\n", settings.SMTPTLS)
+	return nil
+}
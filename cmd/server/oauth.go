@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-presence-mcp/internal/oauth"
+)
+
+// oauthLoginTimeout bounds how long RunOAuthLogin waits for the browser redirect
+const oauthLoginTimeout = 5 * time.Minute
+
+// RunOAuthLogin authorizes an account through its OAuth provider and stores
+// the refresh token used by the server. Output goes to stdout since this
+// runs as a standalone CLI command.
+func RunOAuthLogin(account string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	emailConfig, err := cfg.GetEmailAccount(account)
+	if err != nil {
+		return err
+	}
+	if emailConfig.OAuth == nil {
+		return fmt.Errorf("account %s has no oauth block in its configuration", emailConfig.Username)
+	}
+
+	store, err := oauth.OpenStore(cfg.Tokens)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oauthLoginTimeout)
+	defer cancel()
+
+	err = oauth.Login(ctx, emailConfig.Username, emailConfig.OAuth, store, func(authURL string) {
+		fmt.Printf("Open this URL in a browser to authorize %s:\n\n%s\n\nWaiting for the redirect...\n", emailConfig.Username, authURL)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stored OAuth refresh token for %s in %s.\n", emailConfig.Username, cfg.Tokens.Path)
+	return nil
+}
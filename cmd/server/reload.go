@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"ai-presence-mcp/internal/approval"
+	"ai-presence-mcp/internal/config"
+	"ai-presence-mcp/internal/email"
+	"ai-presence-mcp/internal/logging"
+	"ai-presence-mcp/internal/mcp"
+	"ai-presence-mcp/internal/oauth"
+)
+
+// runtime holds the long-lived server components and applies configurations
+// to them, both at startup and when the configuration is reloaded
+type runtime struct {
+	server    *mcp.Server
+	approvals *approval.Manager
+	service   *email.Service
+	outbox    *email.Outbox
+
+	statusTool     mcp.Tool
+	emailTools     []mcp.Tool
+	sendTools      []mcp.Tool
+	emailResources []mcp.ResourceTemplate
+	emailPrompts   []mcp.Prompt
+	mailWatcher    *email.MailWatcher
+	oauthReady     bool
+}
+
+func newRuntime(server *mcp.Server, approvals *approval.Manager, service *email.Service, outbox *email.Outbox) *runtime {
+	rt := &runtime{
+		server:    server,
+		approvals: approvals,
+		service:   service,
+		outbox:    outbox,
+		emailTools: []mcp.Tool{
+			email.NewListAccountsTool(service),
+			email.NewReadEmailsTool(service),
+			email.NewGetEmailContentTool(service),
+			email.NewSummarizeEmailTool(service),
+			email.NewClassifyInboxTool(service),
+			email.NewGetQuotaTool(service),
+		},
+		sendTools: []mcp.Tool{
+			email.NewSendEmailTool(service, outbox),
+			email.NewListOutboxTool(outbox),
+			email.NewCancelScheduledTool(outbox),
+		},
+		emailResources: []mcp.ResourceTemplate{
+			email.NewMessageResource(service),
+			email.NewFolderResource(service),
+		},
+		emailPrompts: []mcp.Prompt{
+			email.NewTriageInboxPrompt(service),
+			email.NewDraftReplyPrompt(service),
+			email.NewSummarizeThreadPrompt(service),
+			email.NewWeeklyDigestPrompt(service),
+		},
+		mailWatcher: email.NewMailWatcher(service, server.ResourceUpdated),
+	}
+	if approvals.Enabled() {
+		rt.statusTool = approval.NewStatusTool(approvals)
+	}
+	return rt
+}
+
+// apply hands cfg's accounts to the email service and registers the tools it
+// enables. Operations already in progress finish with the previous accounts.
+func (rt *runtime) apply(cfg *config.Config) error {
+	if oauth.Configured(cfg.Email) && !rt.oauthReady {
+		tokens, err := oauth.OpenStore(cfg.Tokens)
+		if err != nil {
+			return fmt.Errorf("failed to open OAuth token store: %w", err)
+		}
+		rt.service.SetTokenProvider(oauth.NewManager(tokens))
+		rt.oauthReady = true
+	}
+
+	if err := logging.SetLevel(cfg.Server.LogLevel); err != nil {
+		return err
+	}
+	logging.SetSecrets(cfg.Secrets())
+
+	rt.service.SetDryRun(cfg.Server.DryRun)
+	rt.server.SetToolTimeouts(cfg.Server.ToolTimeout, cfg.Server.ToolTimeouts)
+	if cfg.Server.DryRun {
+		log.Printf("Dry-run mode enabled: emails will be rendered but never sent")
+	}
+	rt.service.SetAccounts(cfg.Email)
+
+	var tools []mcp.Tool
+	var templates []mcp.ResourceTemplate
+	var prompts []mcp.Prompt
+	if rt.statusTool != nil {
+		tools = append(tools, rt.statusTool)
+	}
+	// Email tools, resources and prompts are only offered while at least
+	// one account is configured
+	if len(cfg.Email) > 0 {
+		tools = append(tools, rt.emailTools...)
+		// Sending and the outbox are left out when every account is
+		// read_only or no_send
+		if email.AnyCanSend(cfg.Email) {
+			tools = append(tools, rt.sendTools...)
+		}
+		templates = rt.emailResources
+		prompts = rt.emailPrompts
+		log.Printf("Registered email tools for %d accounts", len(cfg.Email))
+	}
+	rt.server.SetTools(tools)
+	rt.server.SetResources(templates, rt.service.AccountResources())
+	rt.server.SetPrompts(prompts)
+	return nil
+}
+
+// watchMail checks the folders clients are subscribed to for new mail every
+// interval, notifying the subscribers of those that changed
+func (rt *runtime) watchMail(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			rt.mailWatcher.Check(ctx, rt.server.Subscriptions())
+		}
+	}
+}
+
+// watch reloads the configuration when the file changes or on SIGHUP, and
+// periodically refreshes secret references. An invalid configuration is
+// logged and the current one kept.
+func (rt *runtime) watch(ctx context.Context, cfg *config.Config) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	stamp := statConfig()
+	fileChanges := newTicker(cfg.Server.WatchInterval)
+	defer fileChanges.Stop()
+	secretRefresh := newTicker(cfg.Server.SecretRefresh)
+	defer secretRefresh.Stop()
+
+	reload := func(reason string) {
+		next, err := loadConfig()
+		if err != nil {
+			log.Printf("Warning: keeping the current configuration, reload failed: %v", err)
+			return
+		}
+		if err := rt.apply(next); err != nil {
+			log.Printf("Warning: failed to apply reloaded configuration: %v", err)
+			return
+		}
+		warnRestartRequired(cfg, next)
+		cfg = next
+		fileChanges.Reset(cfg.Server.WatchInterval)
+		secretRefresh.Reset(cfg.Server.SecretRefresh)
+		log.Printf("Reloaded configuration (%s): %d email accounts", reason, len(cfg.Email))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			stamp = statConfig()
+			reload("SIGHUP")
+		case <-fileChanges.C():
+			if next := statConfig(); next != stamp {
+				stamp = next
+				reload("file changed")
+			}
+		case <-secretRefresh.C():
+			if !cfg.HasSecretRefs() {
+				continue
+			}
+			changed, err := cfg.RefreshSecrets()
+			if err != nil {
+				log.Printf("Warning: keeping previous secrets: %v", err)
+				continue
+			}
+			if changed {
+				logging.SetSecrets(cfg.Secrets())
+				rt.service.SetAccounts(cfg.Email)
+				log.Printf("Reloaded changed account secrets")
+			}
+		}
+	}
+}
+
+// fileStamp identifies a version of the config file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statConfig returns the stamp of the config file, or a zero stamp if it is missing
+func statConfig() fileStamp {
+	info, err := os.Stat(configPath())
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// warnRestartRequired logs the changed settings a reload cannot apply
+func warnRestartRequired(old, next *config.Config) {
+	for _, setting := range []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"server.host", old.Server.Host, next.Server.Host},
+		{"server.port", old.Server.Port, next.Server.Port},
+		{"server.auth_token", old.Server.AuthToken, next.Server.AuthToken},
+		{"server.allowed_origins", old.Server.AllowedOrigins, next.Server.AllowedOrigins},
+		{"server.log_file", old.Server.LogFile, next.Server.LogFile},
+		{"server.mail_check_interval", old.Server.MailCheckInterval, next.Server.MailCheckInterval},
+		{"outbox", old.Outbox, next.Outbox},
+		{"approval", old.Approval, next.Approval},
+		{"rate_limits", old.RateLimits, next.RateLimits},
+		{"tokens", old.Tokens, next.Tokens},
+	} {
+		if !reflect.DeepEqual(setting.old, setting.new) {
+			log.Printf("Warning: changes to %s take effect after a restart", setting.name)
+		}
+	}
+}
+
+// ticker is a time.Ticker that can be disabled with a zero interval
+type ticker struct {
+	t *time.Ticker
+}
+
+func newTicker(interval time.Duration) *ticker {
+	t := &ticker{t: time.NewTicker(time.Hour)}
+	t.Reset(interval)
+	return t
+}
+
+// C returns the tick channel, which never fires while the ticker is disabled
+func (t *ticker) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Reset changes the interval; zero or negative disables the ticker
+func (t *ticker) Reset(interval time.Duration) {
+	if interval <= 0 {
+		t.t.Stop()
+		return
+	}
+	t.t.Reset(interval)
+}
+
+func (t *ticker) Stop() {
+	t.t.Stop()
+}
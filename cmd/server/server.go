@@ -2,73 +2,202 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"ai-presence-mcp/internal/approval"
 	"ai-presence-mcp/internal/config"
 	"ai-presence-mcp/internal/email"
+	httpapi "ai-presence-mcp/internal/http"
+	"ai-presence-mcp/internal/logging"
 	"ai-presence-mcp/internal/mcp"
+	"ai-presence-mcp/internal/ratelimit"
 
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-func Run(testMode bool) error {
+// Run starts the server on transport, one of mcp.Transports
+func Run(testMode bool, transport string) error {
 	// Configure logger to use stderr (stdout must be reserved for JSON-RPC in MCP)
 	log.SetOutput(os.Stderr)
 
-	// Get config path from environment variable or use default
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config.yaml"
+	if err := mcp.CheckTransport(transport); err != nil {
+		return err
 	}
 
-	// Load configuration
-	cfg, err := config.Load(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
-		log.Printf("Warning: Could not load config file, using defaults: %v", err)
-		cfg = &config.Config{
-			Server: config.ServerConfig{
-				Port:     8080,
-				LogLevel: "info",
-			},
-		}
+		return err
+	}
+
+	closeLog, err := logging.Setup(cfg.Server.LogLevel, cfg.Server.LogFile)
+	if err != nil {
+		return err
 	}
+	defer closeLog()
 
 	log.Printf("Starting AI Presence MCP Server...")
 
 	// Create MCP server
 	server := mcp.NewServer()
 
-	// Register email tools if email config is available
-	if len(cfg.Email) > 0 {
-		emailService := email.NewService(cfg.Email)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Clients that ask for it with logging/setLevel receive the log too
+	logging.Forward(server.LogHandler(ctx))
+	defer logging.Forward(nil)
 
-		sendEmailTool := email.NewSendEmailTool(emailService)
-		readEmailsTool := email.NewReadEmailsTool(emailService)
-		getEmailContentTool := email.NewGetEmailContentTool(emailService)
+	// Gate outbound tools behind human approval if configured
+	approvals := approval.NewManager(cfg.Approval)
+	if approvals.Enabled() {
+		server.SetApprovals(approvals)
+		log.Printf("Human approval required for: %v", cfg.Approval.Tools)
 
-		server.RegisterTool(sendEmailTool)
-		server.RegisterTool(readEmailsTool)
-		server.RegisterTool(getEmailContentTool)
+		if !testMode {
+			go approvals.Run(ctx)
+		}
+	}
+
+	// The email service and outbox live for the whole process; reloading the
+	// configuration swaps their accounts and the registered tools
+	emailService := email.NewService(cfg.Email)
+	limits, err := ratelimit.Open(cfg.RateLimits.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open rate limit state: %w", err)
+	}
+	emailService.SetLimiter(email.NewLimiter(limits))
+
+	outbox, err := email.NewOutbox(emailService, cfg.Outbox)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
 
-		log.Printf("Registered email tools for %d accounts", len(cfg.Email))
+	rt := newRuntime(server, approvals, emailService, outbox)
+	if err := rt.apply(cfg); err != nil {
+		return err
 	}
 
 	if testMode {
 		return runTestMode(server, cfg)
 	}
 
+	go outbox.Run(ctx)
+	go rt.watch(ctx, cfg)
+	go rt.watchMail(ctx, cfg.Server.MailCheckInterval)
+
+	if transport != mcp.TransportStdio {
+		api := httpapi.NewServer(emailService, outbox, approvals, cfg.Approval.HTTPToken)
+		return serveHTTP(ctx, server, api, transport, cfg.Server)
+	}
+
 	// Start MCP server with stdio transport (standard MCP protocol)
 	log.Printf("MCP Server ready. Listening on stdin/stdout...")
 
-	transport := &sdkmcp.StdioTransport{}
-	ctx := context.Background()
+	if err := server.Run(ctx, &sdkmcp.StdioTransport{}); err != nil {
+		return fmt.Errorf("failed to run MCP server: %w", err)
+	}
+
+	return nil
+}
+
+// serveHTTP serves the MCP server over an HTTP transport, with the REST API
+// next to it, until interrupted, then lets requests in flight finish
+func serveHTTP(ctx context.Context, server *mcp.Server, api *httpapi.Server, transport string, cfg config.ServerConfig) error {
+	handler, err := server.HTTPHandler(transport)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(mcp.HTTPPath, httpapi.Protect(handler, cfg.AuthToken, cfg.AllowedOrigins))
+	api.Mount(mux, cfg.AuthToken, cfg.AllowedOrigins)
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+	log.Printf("MCP Server ready. Listening on http://%s%s (%s)...", addr, mcp.HTTPPath, transport)
+	if cfg.AuthToken == "" {
+		log.Printf("Warning: no server.auth_token set; any local process can use the server")
+	}
 
-	if err := server.Run(ctx, transport); err != nil {
+	select {
+	case err := <-errs:
 		return fmt.Errorf("failed to run MCP server: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down MCP server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down MCP server: %w", err)
+	}
+	return nil
+}
+
+// shutdownTimeout bounds how long the HTTP transports wait for requests in
+// flight when shutting down
+const shutdownTimeout = 10 * time.Second
+
+// configPath returns the configuration file named by CONFIG_PATH, defaulting
+// to config.yaml
+func configPath() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
+	}
+	return "config.yaml"
+}
+
+// loadConfig reads and validates the configuration file. Only a missing
+// config.yaml at the default location falls back to defaults and
+// environment overrides; an explicitly named file that is missing,
+// unparsable or invalid is an error.
+func loadConfig() (*config.Config, error) {
+	path := configPath()
+
+	cfg, err := config.Load(path)
+	if errors.Is(err, os.ErrNotExist) && os.Getenv("CONFIG_PATH") == "" {
+		log.Printf("Warning: %s not found, using defaults and environment overrides", path)
+		cfg, err = config.Load("")
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// CheckConfig loads and validates the configuration without starting the
+// server, printing the result to stdout
+func CheckConfig() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: OK (%d email accounts)\n", configPath(), len(cfg.Email))
 	return nil
 }
 
@@ -81,6 +210,23 @@ func runTestMode(server *mcp.Server, cfg *config.Config) error {
 		log.Printf("⚠️  No email configuration found")
 	}
 
+	// Answer a tools/list request as a client would
+	response, err := server.HandleMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if err != nil {
+		return fmt.Errorf("failed to list tools: %w", err)
+	}
+	var list struct {
+		Result struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(response, &list); err != nil {
+		return fmt.Errorf("failed to parse tools/list response: %w", err)
+	}
+	log.Printf("✅ tools/list returned %d tools", len(list.Result.Tools))
+
 	log.Printf("✅ Test mode completed! Server would be ready to accept MCP connections.")
 	return nil
 }
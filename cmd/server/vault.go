@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"ai-presence-mcp/internal/config"
+	"ai-presence-mcp/internal/vault"
+
+	"golang.org/x/term"
+)
+
+// Vault subcommands accepted by RunVaultCommand
+const (
+	VaultList   = "list"
+	VaultAdd    = "add"
+	VaultRotate = "rotate"
+	VaultRemove = "remove"
+)
+
+// RunVaultCommand manages the credentials in the encrypted vault. add and
+// rotate read the secret from stdin so it never appears in the process list
+// or shell history. Output goes to stdout since this runs as a standalone
+// CLI command.
+func RunVaultCommand(action, name string) error {
+	path := configPath()
+	vaultConfig, err := config.LoadVaultConfig(path)
+	if errors.Is(err, os.ErrNotExist) && os.Getenv("CONFIG_PATH") == "" {
+		vaultConfig, err = config.LoadVaultConfig("")
+	}
+	if err != nil {
+		return err
+	}
+
+	v, err := vault.Open(vaultConfig)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case VaultList:
+		entries, err := v.List()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Printf("The vault %s holds no credentials.\n", vaultConfig.Path)
+			return nil
+		}
+		for _, e := range entries {
+			fmt.Printf("%s\t(updated %s)\n", e.Name, e.UpdatedAt.Local().Format(time.RFC1123))
+		}
+	case VaultAdd, VaultRotate:
+		secret, err := readSecret(os.Stdin, name)
+		if err != nil {
+			return err
+		}
+		if action == VaultAdd {
+			err = v.Add(name, secret)
+		} else {
+			err = v.Rotate(name, secret)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Stored the credential for %s in %s. Reference it as password: \"%s%s\".\n",
+			name, vaultConfig.Path, vault.Prefix, name)
+	case VaultRemove:
+		if err := v.Remove(name); err != nil {
+			return err
+		}
+		fmt.Printf("Removed the credential for %s.\n", name)
+	default:
+		return fmt.Errorf("unknown vault command %q", action)
+	}
+
+	return nil
+}
+
+// readSecret reads one line from r. When r is a terminal it prompts on
+// stderr and reads without echoing what is typed.
+func readSecret(r *os.File, name string) (string, error) {
+	var line string
+	if fd := int(r.Fd()); term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "Credential for %s: ", name)
+		data, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read credential: %w", err)
+		}
+		line = string(data)
+	} else {
+		var err error
+		line, err = bufio.NewReader(r).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read credential: %w", err)
+		}
+	}
+
+	secret := strings.TrimRight(line, "\r\n")
+	if secret == "" {
+		return "", errors.New("no credential given on stdin")
+	}
+	return secret, nil
+}
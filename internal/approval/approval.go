@@ -0,0 +1,366 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+	"ai-presence-mcp/pkg/utils"
+)
+
+// Approval modes
+const (
+	ModeOff      = "off"
+	ModeRequired = "required"
+)
+
+// Pending action states
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+	StatusExpired  = "expired"
+	StatusExecuted = "executed"
+	StatusFailed   = "failed"
+)
+
+// Executor runs a tool call once it has been approved
+type Executor func(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error)
+
+// Action is a tool call waiting for, or resolved by, a human decision
+type Action struct {
+	Token      string                 `json:"token"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments"`
+	Preview    string                 `json:"preview"`
+	Status     string                 `json:"status"`
+	CreatedAt  time.Time              `json:"created_at"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+	DecidedAt  *time.Time             `json:"decided_at,omitempty"`
+	DecidedBy  string                 `json:"decided_by,omitempty"`
+	Result     string                 `json:"result,omitempty"`
+	ExecutedAt *time.Time             `json:"executed_at,omitempty"`
+}
+
+// Manager tracks pending actions in a file shared between the server and
+// the approval CLI. Every mutation re-reads the file under a lock so that
+// decisions made by another process are never overwritten.
+type Manager struct {
+	config types.ApprovalConfig
+	tools  map[string]bool
+
+	mu        sync.Mutex
+	executors map[string]Executor
+}
+
+// NewManager creates a manager for the given configuration
+func NewManager(config types.ApprovalConfig) *Manager {
+	tools := make(map[string]bool, len(config.Tools))
+	for _, name := range config.Tools {
+		tools[name] = true
+	}
+
+	return &Manager{
+		config:    config,
+		tools:     tools,
+		executors: make(map[string]Executor),
+	}
+}
+
+// Enabled reports whether approval is required for any tool
+func (m *Manager) Enabled() bool {
+	return m.config.Mode == ModeRequired
+}
+
+// Elicit reports whether approval should first be requested from the user
+// through the MCP client before falling back to a pending action
+func (m *Manager) Elicit() bool {
+	return m.config.Elicit
+}
+
+// Requires reports whether calls to the named tool need a human decision
+func (m *Manager) Requires(tool string) bool {
+	return m.Enabled() && m.tools[tool]
+}
+
+// RegisterExecutor sets the function used to run approved calls of a tool
+func (m *Manager) RegisterExecutor(tool string, exec Executor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.executors[tool] = exec
+}
+
+// Submit records a new pending action and returns it
+func (m *Manager) Submit(tool string, args map[string]interface{}, preview string) (*Action, error) {
+	token, err := utils.GenerateRandomToken(18)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate approval token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	action := &Action{
+		Token:     token,
+		Tool:      tool,
+		Arguments: args,
+		Preview:   preview,
+		Status:    StatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.config.TTL),
+	}
+
+	err = m.update(func(actions []*Action) ([]*Action, error) {
+		return append(actions, action), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Approval required for %s (token %s, expires %s)", tool, token, action.ExpiresAt.Format(time.RFC3339))
+	return action, nil
+}
+
+// Get returns the action with the given token
+func (m *Manager) Get(token string) (*Action, error) {
+	var found *Action
+	err := m.update(func(actions []*Action) ([]*Action, error) {
+		found = findAction(actions, token)
+		return actions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("approval token not found: %s", token)
+	}
+	return found, nil
+}
+
+// List returns actions with the given status, newest first. An empty status
+// returns every action.
+func (m *Manager) List(status string) ([]Action, error) {
+	result := []Action{}
+	err := m.update(func(actions []*Action) ([]*Action, error) {
+		for _, a := range actions {
+			if status == "" || a.Status == status {
+				result = append(result, *a)
+			}
+		}
+		return actions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// Approve marks a pending action as approved. If this process can execute
+// the tool, the action runs immediately; otherwise the server that created
+// it picks it up on its next poll.
+func (m *Manager) Approve(token, by string) (*Action, error) {
+	action, err := m.decide(token, by, StatusApproved)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	_, canExecute := m.executors[action.Tool]
+	m.mu.Unlock()
+
+	if canExecute {
+		// The call outlives whoever approved it, e.g. an HTTP request
+		m.executeApproved(context.Background())
+		return m.Get(token)
+	}
+
+	return action, nil
+}
+
+// Deny rejects a pending action
+func (m *Manager) Deny(token, by string) (*Action, error) {
+	return m.decide(token, by, StatusDenied)
+}
+
+// Run executes actions approved from other processes and expires stale ones
+// until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		m.executeApproved(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) decide(token, by, status string) (*Action, error) {
+	var decided Action
+	err := m.update(func(actions []*Action) ([]*Action, error) {
+		action := findAction(actions, token)
+		if action == nil {
+			return nil, fmt.Errorf("approval token not found: %s", token)
+		}
+		if action.Status != StatusPending {
+			return nil, fmt.Errorf("action %s is %s and can no longer be decided", token, action.Status)
+		}
+
+		now := time.Now().UTC()
+		action.Status = status
+		action.DecidedAt = &now
+		action.DecidedBy = by
+		decided = *action
+		return actions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Action %s (%s) %s by %s", token, decided.Tool, status, by)
+	return &decided, nil
+}
+
+// executeApproved claims every approved action this process has an executor
+// for and runs it
+func (m *Manager) executeApproved(ctx context.Context) {
+	m.mu.Lock()
+	executors := make(map[string]Executor, len(m.executors))
+	for name, exec := range m.executors {
+		executors[name] = exec
+	}
+	m.mu.Unlock()
+
+	var claimed []Action
+	err := m.update(func(actions []*Action) ([]*Action, error) {
+		now := time.Now().UTC()
+		for _, a := range actions {
+			if a.Status != StatusApproved || executors[a.Tool] == nil {
+				continue
+			}
+			// Mark as executed up front so no other process runs it again
+			a.Status = StatusExecuted
+			a.ExecutedAt = &now
+			claimed = append(claimed, *a)
+		}
+		return actions, nil
+	})
+	if err != nil {
+		log.Printf("Approval: %v", err)
+		return
+	}
+
+	for _, action := range claimed {
+		status, text := StatusExecuted, ""
+
+		result, err := executors[action.Tool](ctx, action.Arguments)
+		switch {
+		case err != nil:
+			status, text = StatusFailed, err.Error()
+		case result != nil:
+			for _, c := range result.Content {
+				text += c.Text
+			}
+			if result.IsError != nil && *result.IsError {
+				status = StatusFailed
+			}
+		}
+
+		token := action.Token
+		err = m.update(func(actions []*Action) ([]*Action, error) {
+			if a := findAction(actions, token); a != nil {
+				a.Status = status
+				a.Result = text
+			}
+			return actions, nil
+		})
+		if err != nil {
+			log.Printf("Approval: %v", err)
+		}
+		log.Printf("Approved action %s (%s) %s", token, action.Tool, status)
+	}
+}
+
+// update loads the action file under a lock, expires stale actions, applies
+// fn and writes the result back
+func (m *Manager) update(fn func([]*Action) ([]*Action, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := utils.LockFile(m.config.Path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	actions, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, a := range actions {
+		if a.Status == StatusPending && now.After(a.ExpiresAt) {
+			a.Status = StatusExpired
+		}
+	}
+
+	actions, err = fn(actions)
+	if err != nil {
+		return err
+	}
+
+	return m.save(actions)
+}
+
+func (m *Manager) load() ([]*Action, error) {
+	data, err := os.ReadFile(m.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approvals: %w", err)
+	}
+
+	var actions []*Action
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &actions); err != nil {
+			return nil, fmt.Errorf("failed to parse approvals %s: %w", m.config.Path, err)
+		}
+	}
+	return actions, nil
+}
+
+func (m *Manager) save(actions []*Action) error {
+	data, err := json.MarshalIndent(actions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode approvals: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(m.config.Path, data); err != nil {
+		return fmt.Errorf("failed to write approvals: %w", err)
+	}
+	return nil
+}
+
+func findAction(actions []*Action, token string) *Action {
+	for _, a := range actions {
+		if a.Token == token {
+			return a
+		}
+	}
+	return nil
+}
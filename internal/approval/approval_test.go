@@ -0,0 +1,118 @@
+package approval
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func testConfig(t *testing.T) types.ApprovalConfig {
+	t.Helper()
+	return types.ApprovalConfig{
+		Mode:         ModeRequired,
+		Tools:        []string{"send_email"},
+		TTL:          time.Minute,
+		Path:         filepath.Join(t.TempDir(), "approvals.json"),
+		PollInterval: time.Second,
+	}
+}
+
+func TestRequires(t *testing.T) {
+	cfg := testConfig(t)
+	m := NewManager(cfg)
+	if !m.Requires("send_email") {
+		t.Error("expected send_email to require approval")
+	}
+	if m.Requires("read_emails") {
+		t.Error("expected read_emails not to require approval")
+	}
+
+	cfg.Mode = ModeOff
+	if NewManager(cfg).Requires("send_email") {
+		t.Error("expected no approval when mode is off")
+	}
+}
+
+func TestApproveFromAnotherProcess(t *testing.T) {
+	cfg := testConfig(t)
+
+	server := NewManager(cfg)
+	executed := 0
+	server.RegisterExecutor("send_email", func(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+		executed++
+		return &types.ToolResult{Content: []types.ToolContent{{Type: "text", Text: "sent to " + args["to"].(string)}}}, nil
+	})
+
+	action, err := server.Submit("send_email", map[string]interface{}{"to": "friend@example.com"}, "preview")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	// The CLI has no executors, so approving only records the decision
+	cli := NewManager(cfg)
+	approved, err := cli.Approve(action.Token, "cli")
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if approved.Status != StatusApproved || executed != 0 {
+		t.Fatalf("expected approved but not executed, got %s with %d executions", approved.Status, executed)
+	}
+
+	server.executeApproved(context.Background())
+	server.executeApproved(context.Background())
+
+	got, err := server.Get(action.Token)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if executed != 1 {
+		t.Errorf("expected exactly one execution, got %d", executed)
+	}
+	if got.Status != StatusExecuted || got.Result != "sent to friend@example.com" {
+		t.Errorf("unexpected action after execution: %+v", got)
+	}
+
+	if _, err := cli.Deny(action.Token, "cli"); err == nil {
+		t.Error("expected deciding an executed action to fail")
+	}
+}
+
+func TestDenyAndExpire(t *testing.T) {
+	cfg := testConfig(t)
+	m := NewManager(cfg)
+
+	denied, err := m.Submit("send_email", nil, "preview")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if _, err := m.Deny(denied.Token, "test"); err != nil {
+		t.Fatalf("Deny: %v", err)
+	}
+	if _, err := m.Approve(denied.Token, "test"); err == nil {
+		t.Error("expected approving a denied action to fail")
+	}
+
+	cfg.TTL = -time.Second
+	stale, err := NewManager(cfg).Submit("send_email", nil, "preview")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	got, err := m.Get(stale.Token)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != StatusExpired {
+		t.Errorf("expected expired, got %s", got.Status)
+	}
+
+	pending, err := m.List(StatusPending)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending actions, got %d", len(pending))
+	}
+}
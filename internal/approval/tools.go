@@ -0,0 +1,139 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// Previewer is implemented by tools that can describe what a call would do
+// before it runs. The preview is shown to the human approving the call.
+type Previewer interface {
+	Preview(args map[string]interface{}) (string, error)
+}
+
+// ReadOnlyCaller is implemented by tools for which some calls have no side
+// effects (such as dry runs). Those calls skip approval.
+type ReadOnlyCaller interface {
+	ReadOnlyCall(args map[string]interface{}) bool
+}
+
+// NeedsApproval reports whether this particular call of a gated tool must be
+// approved
+func NeedsApproval(tool interface{}, args map[string]interface{}) bool {
+	if ro, ok := tool.(ReadOnlyCaller); ok && ro.ReadOnlyCall(args) {
+		return false
+	}
+	return true
+}
+
+// Describe returns the preview for a tool call, falling back to the raw
+// arguments for tools that do not implement Previewer
+func Describe(name string, tool interface{}, args map[string]interface{}) (string, error) {
+	if p, ok := tool.(Previewer); ok {
+		return p.Preview(args)
+	}
+
+	data, err := json.MarshalIndent(args, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arguments: %w", err)
+	}
+	return fmt.Sprintf("Tool: %s\nArguments:\n%s", name, data), nil
+}
+
+// PendingResult is the tool result returned to the client when a call has
+// been held for approval. It names the HTTP endpoint only when approving over
+// HTTP is enabled.
+func (m *Manager) PendingResult(action *Action) *types.ToolResult {
+	how := fmt.Sprintf("`sapphire-duck -approve %s`", action.Token)
+	if m.config.HTTPToken != "" {
+		how += " or, when the server runs over HTTP, POST /api/v1/approvals/approve"
+	}
+	text := fmt.Sprintf("This action requires human approval and has NOT been performed yet.\n\n"+
+		"%s\n\n"+
+		"Approval token: %s\n"+
+		"Expires at: %s\n\n"+
+		"Ask the user to approve it with %s. "+
+		"Use the approval_status tool with this token to check the outcome.",
+		action.Preview, action.Token, action.ExpiresAt.Format(time.RFC3339), how)
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: text,
+		}},
+	}
+}
+
+// StatusTool implements the MCP Tool interface for checking a pending action
+type StatusTool struct {
+	manager *Manager
+}
+
+func NewStatusTool(manager *Manager) *StatusTool {
+	return &StatusTool{manager: manager}
+}
+
+func (t *StatusTool) Name() string {
+	return "approval_status"
+}
+
+func (t *StatusTool) Description() string {
+	return "Check whether an action that was held for human approval has been approved, denied, expired or executed, and see its result."
+}
+
+func (t *StatusTool) InputSchema() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"token": map[string]interface{}{
+				"type":        "string",
+				"description": "Approval token returned when the action was submitted",
+			},
+		},
+		"required": []string{"token"},
+	}
+}
+
+func (t *StatusTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	token, ok := args["token"].(string)
+	if !ok || token == "" {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: "Error: 'token' parameter is required and must be a string",
+			}},
+			IsError: &[]bool{true}[0],
+		}, nil
+	}
+
+	action, err := t.manager.Get(token)
+	if err != nil {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Error: %v", err),
+			}},
+			IsError: &[]bool{true}[0],
+		}, nil
+	}
+
+	result := fmt.Sprintf("Tool: %s\nStatus: %s\nCreated: %s\nExpires: %s\n",
+		action.Tool, action.Status, action.CreatedAt.Format(time.RFC3339), action.ExpiresAt.Format(time.RFC3339))
+	if action.DecidedBy != "" {
+		result += fmt.Sprintf("Decided by: %s\n", action.DecidedBy)
+	}
+	if action.Result != "" {
+		result += fmt.Sprintf("\n--- Result ---\n%s\n", action.Result)
+	}
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: result,
+		}},
+	}, nil
+}
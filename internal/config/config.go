@@ -1,9 +1,15 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
+	"ai-presence-mcp/internal/email"
+	"ai-presence-mcp/internal/vault"
 	"ai-presence-mcp/pkg/types"
 	"gopkg.in/yaml.v3"
 )
@@ -11,21 +17,120 @@ import (
 type Config struct {
 	Server ServerConfig `yaml:"server"`
 	Email  []types.EmailConfig `yaml:"email"`
+	Outbox types.OutboxConfig  `yaml:"outbox"`
+	Approval types.ApprovalConfig `yaml:"approval"`
+	RateLimits types.RateLimitConfig `yaml:"rate_limits"`
+	Tokens types.TokenStoreConfig `yaml:"tokens"`
+	Vault types.VaultConfig `yaml:"vault"`
+	Discovery types.DiscoveryConfig `yaml:"discovery"`
+
+	// secretRefs maps the YAML path of each secret loaded from a reference
+	// to that reference
+	secretRefs map[string]string
+	// ambiguousSecrets lists the paths of literal secrets that look like
+	// references, for Validate to warn about
+	ambiguousSecrets []string
+	// vault is opened on first use by a vault: reference
+	vault *vault.Vault
 }
 
 type ServerConfig struct {
+	// Host is the address the HTTP transports listen on. Anything other than
+	// a loopback address requires AuthToken.
+	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
+	// AuthToken is the bearer token HTTP clients must send; empty lets any
+	// local client in
+	AuthToken string `yaml:"auth_token"`
+	// AllowedOrigins lists the browser origins allowed to call the HTTP
+	// transports; requests from any other origin are rejected
+	AllowedOrigins []string `yaml:"allowed_origins"`
 	LogLevel string `yaml:"log_level"`
+	// LogFile receives the log instead of stderr
+	LogFile  string `yaml:"log_file"`
+	DryRun   bool   `yaml:"dry_run"`
+	// SecretRefresh re-resolves secret references at this interval; 0 disables it
+	SecretRefresh time.Duration `yaml:"secret_refresh"`
+	// WatchInterval is how often the config file is checked for changes; 0
+	// disables watching, leaving SIGHUP as the only way to reload
+	WatchInterval time.Duration `yaml:"watch_interval"`
+	// MailCheckInterval is how often subscribed folders are checked for new
+	// mail; 0 disables the checks
+	MailCheckInterval time.Duration `yaml:"mail_check_interval"`
+	// ToolTimeout bounds each tool call; 0 lets calls run until the client
+	// cancels them
+	ToolTimeout time.Duration `yaml:"tool_timeout"`
+	// ToolTimeouts overrides ToolTimeout for the named tools
+	ToolTimeouts map[string]time.Duration `yaml:"tool_timeouts"`
 }
 
-func Load(configPath string) (*Config, error) {
-	// Set defaults
-	config := &Config{
+// Default returns the configuration used for anything a config file leaves unset
+func Default() *Config {
+	return &Config{
 		Server: ServerConfig{
+			Host:     "127.0.0.1",
 			Port:     8080,
 			LogLevel: "info",
+			WatchInterval: 2 * time.Second,
+			MailCheckInterval: time.Minute,
+			ToolTimeout: 2 * time.Minute,
+		},
+		Outbox:   DefaultOutboxConfig(),
+		Approval: DefaultApprovalConfig(),
+		RateLimits: types.RateLimitConfig{
+			Path: "ratelimits.json",
 		},
+		Tokens: DefaultTokenStoreConfig(),
+		Vault:  DefaultVaultConfig(),
+		Discovery: types.DiscoveryConfig{
+			Timeout:   10 * time.Second,
+			CachePath: "discovery.json",
+		},
+	}
+}
+
+// Load reads the configuration file on top of the defaults. Unknown keys are
+// rejected so typos do not silently fall back to defaults; call Validate to
+// check the values.
+func Load(configPath string) (*Config, error) {
+	config, err := decode(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config.discoverServers()
+
+	if err := config.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// LoadVaultConfig reads only the vault settings, without resolving any
+// secret references, so the vault can be managed before it holds the
+// credentials the configuration refers to
+func LoadVaultConfig(configPath string) (types.VaultConfig, error) {
+	config, err := decode(configPath)
+	if err != nil {
+		return types.VaultConfig{}, err
+	}
+	return config.Vault, nil
+}
+
+// LoadDiscoveryConfig reads only the autodiscovery settings, without
+// discovering servers or resolving secrets
+func LoadDiscoveryConfig(configPath string) (types.DiscoveryConfig, error) {
+	config, err := decode(configPath)
+	if err != nil {
+		return types.DiscoveryConfig{}, err
 	}
+	return config.Discovery, nil
+}
+
+// decode strictly decodes the configuration file on top of the defaults
+func decode(configPath string) (*Config, error) {
+	config := Default()
 
 	// Try to read config file
 	if configPath != "" {
@@ -34,30 +139,61 @@ func Load(configPath string) (*Config, error) {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 
-		if err := yaml.Unmarshal(data, config); err != nil {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
 			return nil, fmt.Errorf("failed to parse config file: %w", err)
 		}
 	}
 
-	// Override with environment variables
-	if port := os.Getenv("MCP_PORT"); port != "" {
-		// Convert port to int if needed
-		config.Server.Port = 8080 // Simple default for now
+	if err := config.envOverrides(os.Environ()); err != nil {
+		return nil, err
 	}
-
 	return config, nil
 }
 
-func (c *Config) GetEmailAccount(account string) (*types.EmailConfig, error) {
-	if account == "" && len(c.Email) > 0 {
-		return &c.Email[0], nil
+// DefaultOutboxConfig returns the outbox settings used when none are configured
+func DefaultOutboxConfig() types.OutboxConfig {
+	return types.OutboxConfig{
+		Path:           "outbox.json",
+		MaxAttempts:    5,
+		RetryBaseDelay: 30 * time.Second,
+		RetryMaxDelay:  time.Hour,
+		PollInterval:   30 * time.Second,
 	}
+}
 
-	for i := range c.Email {
-		if c.Email[i].Username == account {
-			return &c.Email[i], nil
-		}
+// DefaultApprovalConfig returns the approval settings used when none are
+// configured. Approval is off unless the mode is set to "required".
+func DefaultApprovalConfig() types.ApprovalConfig {
+	return types.ApprovalConfig{
+		Mode:         "off",
+		Elicit:       true,
+		Tools:        []string{"send_email", "cancel_scheduled"},
+		TTL:          15 * time.Minute,
+		Path:         "approvals.json",
+		PollInterval: 5 * time.Second,
 	}
+}
 
-	return nil, fmt.Errorf("email account not found: %s", account)
-}
\ No newline at end of file
+// DefaultTokenStoreConfig returns where OAuth tokens are kept when not configured
+func DefaultTokenStoreConfig() types.TokenStoreConfig {
+	return types.TokenStoreConfig{
+		Path:    "tokens.json",
+		KeyPath: "tokens.key",
+	}
+}
+
+// DefaultVaultConfig returns where the credential vault is kept when not
+// configured. Without a key path the key is derived from a passphrase.
+func DefaultVaultConfig() types.VaultConfig {
+	return types.VaultConfig{
+		Path: "vault.json",
+	}
+}
+
+// GetEmailAccount returns the account with the given name or username, or
+// the default account if account is empty
+func (c *Config) GetEmailAccount(account string) (*types.EmailConfig, error) {
+	return email.FindAccount(c.Email, account)
+}
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-presence-mcp/internal/discovery"
+	"ai-presence-mcp/internal/vault"
+	"ai-presence-mcp/pkg/types"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\n  dry_rn: true\n")
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "dry_rn") {
+		t.Fatalf("expected the misspelt key to be reported, got %v", err)
+	}
+}
+
+func TestLoadKeepsDefaults(t *testing.T) {
+	path := writeConfig(t, "")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Outbox.MaxAttempts != DefaultOutboxConfig().MaxAttempts {
+		t.Errorf("expected defaults for an empty file, got %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected the defaults to be valid, got %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  host: 0.0.0.0
+  log_level: verbose
+  tool_timeout: -1s
+email:
+  - username: agent@example.com
+    password: secret
+    imap_server: imap.example.com
+    imap_port: 993
+    smtp_server: smtp.example.com
+    smtp_port: 70000
+    tls:
+      mode: ssl
+    identities:
+      - name: a
+        default: true
+      - name: a
+        default: true
+  - username: agent@example.com
+    imap_server: imap.example.com
+    imap_port: 993
+    smtp_server: smtp.example.com
+    smtp_port: 587
+approval:
+  mode: sometimes
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	err = cfg.Validate()
+	var problems ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	want := []string{
+		"server.auth_token",
+		"server.log_level",
+		"server.tool_timeout",
+		"email[0].smtp_port",
+		"email[0].tls.mode",
+		"email[0].identities[1].name",
+		"email[0].identities",
+		"email[1].password",
+		"email[1].username",
+		"approval.mode",
+	}
+	got := make(map[string]bool)
+	for _, p := range problems {
+		got[p.Path] = true
+	}
+	for _, path := range want {
+		if !got[path] {
+			t.Errorf("expected a problem at %s, got:\n%v", path, err)
+		}
+	}
+	if len(problems) != len(want) {
+		t.Errorf("expected %d problems, got %d:\n%v", len(want), len(problems), err)
+	}
+}
+
+func TestSecretReferences(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secretFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("SAPPHIREDUCK_TEST_CLIENT_SECRET", "client-secret")
+
+	path := writeConfig(t, `
+email:
+  - username: agent@example.com
+    password: file:`+secretFile+`
+    auth: xoauth2
+    imap_server: imap.example.com
+    imap_port: 993
+    smtp_server: smtp.example.com
+    smtp_port: 587
+    oauth:
+      provider: google
+      client_id: id
+      client_secret: ${SAPPHIREDUCK_TEST_CLIENT_SECRET}
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Email[0].Password != "first" || cfg.Email[0].OAuth.ClientSecret != "client-secret" {
+		t.Fatalf("secrets were not resolved: %q, %q", cfg.Email[0].Password, cfg.Email[0].OAuth.ClientSecret)
+	}
+
+	previous := cfg.Email
+	if changed, err := cfg.RefreshSecrets(); err != nil || changed {
+		t.Fatalf("expected no change, got %v, %v", changed, err)
+	}
+
+	if err := os.WriteFile(secretFile, []byte("second\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	changed, err := cfg.RefreshSecrets()
+	if err != nil || !changed {
+		t.Fatalf("expected the rotated password to be picked up, got %v, %v", changed, err)
+	}
+	if cfg.Email[0].Password != "second" {
+		t.Errorf("expected the new password, got %q", cfg.Email[0].Password)
+	}
+	if previous[0].Password != "first" {
+		t.Error("refresh modified the previous account slice in place")
+	}
+
+	os.Unsetenv("SAPPHIREDUCK_TEST_CLIENT_SECRET")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "email[0].oauth.client_secret") {
+		t.Errorf("expected the unresolved reference to be reported by path, got %v", err)
+	}
+}
+
+func TestLiteralSecrets(t *testing.T) {
+	path := writeConfig(t, `
+email:
+  - username: agent@example.com
+    password: "literal:exec:not a command"
+    imap_server: imap.example.com
+    imap_port: 993
+    smtp_server: smtp.example.com
+    smtp_port: 587
+  - username: other@example.com
+    password: "$not-an-env-var"
+    imap_server: imap.example.com
+    imap_port: 993
+    smtp_server: smtp.example.com
+    smtp_port: 587
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Email[0].Password != "exec:not a command" || cfg.Email[1].Password != "$not-an-env-var" {
+		t.Fatalf("literals were not kept: %q, %q", cfg.Email[0].Password, cfg.Email[1].Password)
+	}
+	if cfg.HasSecretRefs() {
+		t.Error("expected literals not to count as references")
+	}
+	if len(cfg.ambiguousSecrets) != 1 || cfg.ambiguousSecrets[0] != "email[1].password" {
+		t.Errorf("expected only the unescaped $ password to be ambiguous, got %v", cfg.ambiguousSecrets)
+	}
+}
+
+func TestVaultReference(t *testing.T) {
+	dir := t.TempDir()
+	vaultConfig := types.VaultConfig{Path: filepath.Join(dir, "vault.json"), KeyPath: filepath.Join(dir, "vault.key")}
+	v, err := vault.Open(vaultConfig)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := v.Add("work", "from-vault"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	path := writeConfig(t, `
+vault:
+  path: `+vaultConfig.Path+`
+  key_path: `+vaultConfig.KeyPath+`
+email:
+  - username: agent@example.com
+    password: vault:work
+    imap_server: imap.example.com
+    imap_port: 993
+    smtp_server: smtp.example.com
+    smtp_port: 587
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Email[0].Password != "from-vault" {
+		t.Errorf("expected the vault credential, got %q", cfg.Email[0].Password)
+	}
+
+	if err := v.Rotate("work", "rotated"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if changed, err := cfg.RefreshSecrets(); err != nil || !changed || cfg.Email[0].Password != "rotated" {
+		t.Errorf("expected the rotated credential, got %v, %v, %q", changed, err, cfg.Email[0].Password)
+	}
+}
+
+func TestProviderPresets(t *testing.T) {
+	path := writeConfig(t, `
+discovery:
+  offline: true
+email:
+  - provider: icloud
+    username: agent@example.com
+    password: secret
+    smtp_port: 465
+  - provider: hotmail
+    username: other@example.com
+    password: secret
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	account := cfg.Email[0]
+	if account.IMAPServer != "imap.mail.me.com" || account.IMAPPort != 993 ||
+		account.SMTPServer != "smtp.mail.me.com" || account.SMTPPort != 465 {
+		t.Errorf("expected the icloud preset with the explicit SMTP port, got %+v", account)
+	}
+
+	err = cfg.Validate()
+	var problems ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	got := make(map[string]bool)
+	for _, p := range problems {
+		got[p.Path] = true
+	}
+	if !got["email[1].provider"] || !got["email[1].imap_server"] || got["email[0].imap_server"] {
+		t.Errorf("expected only the unknown provider and its missing servers to be reported, got:\n%v", err)
+	}
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "discovery.json")
+	cache, err := discovery.OpenCache(cachePath)
+	if err != nil {
+		t.Fatalf("OpenCache: %v", err)
+	}
+	err = cache.Put("agent@example.org", &discovery.Settings{
+		IMAPServer: "imap.example.org", IMAPPort: 993, IMAPTLS: "implicit",
+		SMTPServer: "smtp.example.org", SMTPPort: 465, SMTPTLS: "implicit",
+		Source: "autoconfig",
+	})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// Offline discovery cannot find example.org, so the servers must come
+	// from the cache
+	path := writeConfig(t, `
+discovery:
+  offline: true
+  cache_path: `+cachePath+`
+email:
+  - username: agent@example.org
+    password: secret
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if account := cfg.Email[0]; account.IMAPServer != "imap.example.org" || account.SMTPPort != 465 {
+		t.Errorf("expected the cached servers, got %+v", account)
+	}
+}
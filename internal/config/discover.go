@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"log"
+
+	"ai-presence-mcp/internal/discovery"
+)
+
+// discoverServers fills in the servers of accounts that leave them out, from
+// the account's provider preset, the discovery cache or else by
+// autodiscovery, whose results are cached. Accounts that cannot be completed
+// are left for Validate to report.
+func (c *Config) discoverServers() {
+	var d *discovery.Discoverer
+	var cache *discovery.Cache
+	for i := range c.Email {
+		account := &c.Email[i]
+
+		if settings, ok := discovery.Preset(account.Provider); ok {
+			discovery.Apply(account, settings)
+		}
+		if discovery.Complete(account) || account.Username == "" {
+			continue
+		}
+
+		if d == nil {
+			d = discovery.New(c.Discovery)
+			cache = c.openDiscoveryCache()
+		}
+		if settings, ok := cache.Get(account.Username); ok {
+			discovery.Apply(account, settings)
+			continue
+		}
+		settings, err := d.Discover(context.Background(), account.Username)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		if settings.Unverified {
+			log.Printf("Warning: not using the mail servers of %s found in %s (%s, %s): they are outside its domain; run -discover and add them to the account if you trust them",
+				account.Username, settings.Source, settings.IMAPServer, settings.SMTPServer)
+			continue
+		}
+		discovery.Apply(account, settings)
+		log.Printf("Discovered mail servers for %s from %s", account.Username, settings.Source)
+		if err := cache.Put(account.Username, settings); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// openDiscoveryCache opens the discovery cache, or returns nil if it is
+// disabled or unreadable, in which case servers are discovered every time
+func (c *Config) openDiscoveryCache() *discovery.Cache {
+	if c.Discovery.CachePath == "" {
+		return nil
+	}
+	cache, err := discovery.OpenCache(c.Discovery.CachePath)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+	return cache
+}
@@ -0,0 +1,350 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides a
+// configuration field
+const EnvPrefix = "SAPPHIREDUCK_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// envOverrides layers environment variables over the configuration. Each
+// field's variable is EnvPrefix followed by its YAML path in upper case,
+// joined by underscores, e.g. SAPPHIREDUCK_SERVER_PORT. List entries are
+// selected by name or index, e.g. SAPPHIREDUCK_EMAIL_WORK_PASSWORD or
+// SAPPHIREDUCK_EMAIL_0_PASSWORD; an unknown name adds an entry with that name.
+// Map entries are set by key, e.g. SAPPHIREDUCK_SERVER_TOOL_TIMEOUTS_SEND_EMAIL.
+func (c *Config) envOverrides(environ []string) error {
+	env := make(map[string]string)
+	mcpPort := ""
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, EnvPrefix) {
+			env[key] = value
+		}
+		if key == "MCP_PORT" {
+			mcpPort = value
+		}
+	}
+
+	// MCP_PORT predates the prefixed variables and is kept as an alias
+	if _, set := env[EnvPrefix+"SERVER_PORT"]; mcpPort != "" && !set {
+		env[EnvPrefix+"SERVER_PORT"] = mcpPort
+	}
+
+	o := &overrider{env: env, used: make(map[string]bool)}
+	o.apply(strings.TrimSuffix(EnvPrefix, "_"), reflect.ValueOf(c).Elem())
+
+	// Other variables may share the prefix, e.g. ones named in secret
+	// references, so unmatched ones are only reported
+	for key := range env {
+		if !o.used[key] && !ignoredEnv[key] {
+			log.Printf("Warning: %s does not match any configuration field", key)
+		}
+	}
+
+	if len(o.errs) > 0 {
+		sort.Slice(o.errs, func(i, j int) bool { return o.errs[i].Path < o.errs[j].Path })
+		return fmt.Errorf("invalid environment overrides: %w", o.errs)
+	}
+	return nil
+}
+
+// ignoredEnv lists variables with the prefix that are not configuration
+// fields, such as encryption keys read directly by their packages
+var ignoredEnv = map[string]bool{
+	"SAPPHIREDUCK_TOKEN_KEY":        true,
+	"SAPPHIREDUCK_VAULT_PASSPHRASE": true,
+}
+
+// overrider walks the configuration, setting fields from env
+type overrider struct {
+	env  map[string]string
+	used map[string]bool
+	errs ValidationErrors
+}
+
+// hasPrefix reports whether any variable starts with prefix followed by an
+// underscore
+func (o *overrider) hasPrefix(prefix string) bool {
+	for key := range o.env {
+		if strings.HasPrefix(key, prefix+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *overrider) apply(name string, v reflect.Value) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := yamlName(t.Field(i))
+			if tag == "" {
+				continue
+			}
+			o.apply(name+"_"+strings.ToUpper(tag), v.Field(i))
+		}
+
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			if !o.hasPrefix(name) {
+				return
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		o.apply(name, v.Elem())
+
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		o.applyList(name, v)
+
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		o.applyMap(name, v)
+
+	default:
+		value, ok := o.env[name]
+		if !ok {
+			return
+		}
+		o.used[name] = true
+		if err := setField(v, value); err != nil {
+			o.errs = append(o.errs, ValidationError{Path: name, Message: err.Error()})
+		}
+	}
+}
+
+// applyList applies the variables of a list of structs. Each variable is
+// split into the entry key and a field path that fits the entry type.
+func (o *overrider) applyList(name string, v reflect.Value) {
+	elem := v.Type().Elem()
+
+	var keys []string
+	seen := make(map[string]bool)
+	for key := range o.env {
+		rest, ok := strings.CutPrefix(key, name+"_")
+		if !ok {
+			continue
+		}
+		for i := strings.Index(rest, "_"); i > 0; {
+			if fits(elem, rest[i+1:]) {
+				if !seen[rest[:i]] {
+					seen[rest[:i]] = true
+					keys = append(keys, rest[:i])
+				}
+				break
+			}
+			next := strings.Index(rest[i+1:], "_")
+			if next < 0 {
+				break
+			}
+			i += next + 1
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		i := findEntry(v, key)
+		if i < 0 {
+			// An unknown name adds an entry carrying that name
+			entry := reflect.New(elem).Elem()
+			if f := entry.FieldByName("Name"); f.IsValid() && f.Kind() == reflect.String {
+				if _, err := strconv.Atoi(key); err != nil {
+					f.SetString(strings.ToLower(key))
+				}
+			}
+			v.Set(reflect.Append(v, entry))
+			i = v.Len() - 1
+		}
+		o.apply(name+"_"+key, v.Index(i))
+	}
+}
+
+// applyMap sets the map entries named by variables under name. A variable
+// replaces the entry whose key it matches, or else adds one with its key in
+// lower case.
+func (o *overrider) applyMap(name string, v reflect.Value) {
+	var keys []string
+	for key := range o.env {
+		if rest, ok := strings.CutPrefix(key, name+"_"); ok && rest != "" {
+			keys = append(keys, rest)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := name + "_" + key
+		o.used[path] = true
+
+		value := reflect.New(v.Type().Elem()).Elem()
+		if err := setField(value, o.env[path]); err != nil {
+			o.errs = append(o.errs, ValidationError{Path: path, Message: err.Error()})
+			continue
+		}
+
+		mapKey := strings.ToLower(key)
+		iter := v.MapRange()
+		for iter.Next() {
+			if envKey(iter.Key().String()) == key {
+				mapKey = iter.Key().String()
+				break
+			}
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(reflect.ValueOf(mapKey).Convert(v.Type().Key()), value)
+	}
+}
+
+// findEntry returns the index of the list entry key selects by index or
+// name, or -1
+func findEntry(v reflect.Value, key string) int {
+	if n, err := strconv.Atoi(key); err == nil {
+		if n >= 0 && n < v.Len() {
+			return n
+		}
+		return -1
+	}
+	for i := 0; i < v.Len(); i++ {
+		f := v.Index(i).FieldByName("Name")
+		if f.IsValid() && f.Kind() == reflect.String && envKey(f.String()) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// fits reports whether path names a field of t
+func fits(t reflect.Type, path string) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Struct && t != durationType:
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.ToUpper(yamlName(t.Field(i)))
+			if tag == "" {
+				continue
+			}
+			if path == tag && !isStructLike(t.Field(i).Type) {
+				return true
+			}
+			if rest, ok := strings.CutPrefix(path, tag+"_"); ok && fits(t.Field(i).Type, rest) {
+				return true
+			}
+		}
+		return false
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		// A nested list: some split into entry key and field must fit
+		for i := strings.Index(path, "_"); i > 0; {
+			if fits(t.Elem(), path[i+1:]) {
+				return true
+			}
+			next := strings.Index(path[i+1:], "_")
+			if next < 0 {
+				return false
+			}
+			i += next + 1
+		}
+		return false
+	case t.Kind() == reflect.Map:
+		return path != ""
+	}
+	return false
+}
+
+func isStructLike(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct)
+}
+
+// yamlName returns the YAML key of an exported field, or "" if it has none
+func yamlName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// envKey converts a name to the form used in variable names
+func envKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// setField parses value into a leaf field
+func setField(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 30s or 5m, got %q", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be an integer, got %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot be set from the environment")
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
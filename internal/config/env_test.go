@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	cfg := Default()
+	cfg.Email = []types.EmailConfig{
+		{Name: "work", Username: "agent@example.com", Password: "from-yaml", IMAPPort: 993},
+	}
+
+	err := cfg.envOverrides([]string{
+		"SAPPHIREDUCK_SERVER_PORT=9000",
+		"SAPPHIREDUCK_SERVER_DRY_RUN=true",
+		"SAPPHIREDUCK_OUTBOX_RETRY_MAX_DELAY=2h",
+		"SAPPHIREDUCK_EMAIL_WORK_PASSWORD=from-env",
+		"SAPPHIREDUCK_EMAIL_WORK_TLS_CA_FILE=/etc/ca.pem",
+		"SAPPHIREDUCK_EMAIL_WORK_USE_TLS=false",
+		"SAPPHIREDUCK_EMAIL_WORK_POLICY_ALLOWED_DOMAINS=example.com, example.org",
+		"SAPPHIREDUCK_EMAIL_WORK_LIMITS_SEND_PER_MINUTE=2.5",
+		"SAPPHIREDUCK_EMAIL_WORK_OAUTH_CLIENT_ID=client",
+		"SAPPHIREDUCK_EMAIL_WORK_IDENTITIES_SUPPORT_FROM=support@example.com",
+		"SAPPHIREDUCK_EMAIL_0_IMAP_PORT=143",
+		"SAPPHIREDUCK_EMAIL_ALERTS_BOT_USERNAME=alerts@example.com",
+		"SAPPHIREDUCK_EMAIL_ALERTS_BOT_NO_SEND=1",
+		"SAPPHIREDUCK_TOKEN_KEY=ignored",
+		"MCP_PORT=1234",
+	})
+	if err != nil {
+		t.Fatalf("envOverrides: %v", err)
+	}
+
+	if cfg.Server.Port != 9000 || !cfg.Server.DryRun || cfg.Outbox.RetryMaxDelay != 2*time.Hour {
+		t.Errorf("server and outbox overrides not applied: %+v, %+v", cfg.Server, cfg.Outbox)
+	}
+
+	work := cfg.Email[0]
+	if work.Password != "from-env" || work.TLS.CAFile != "/etc/ca.pem" || work.UseTLS == nil || *work.UseTLS {
+		t.Errorf("account overrides not applied: %+v", work)
+	}
+	if len(work.Policy.AllowedDomains) != 2 || work.Policy.AllowedDomains[1] != "example.org" {
+		t.Errorf("expected a comma-separated list, got %v", work.Policy.AllowedDomains)
+	}
+	if work.Limits.SendPerMinute != 2.5 || work.IMAPPort != 143 {
+		t.Errorf("numeric overrides not applied: %+v", work)
+	}
+	if work.OAuth == nil || work.OAuth.ClientID != "client" {
+		t.Errorf("expected the oauth block to be created, got %+v", work.OAuth)
+	}
+	if len(work.Identities) != 1 || work.Identities[0].Name != "support" || work.Identities[0].From != "support@example.com" {
+		t.Errorf("expected a support identity, got %+v", work.Identities)
+	}
+
+	if len(cfg.Email) != 2 {
+		t.Fatalf("expected a second account from the environment, got %d", len(cfg.Email))
+	}
+	alerts := cfg.Email[1]
+	if alerts.Name != "alerts_bot" || alerts.Username != "alerts@example.com" || !alerts.NoSend {
+		t.Errorf("unexpected account from the environment: %+v", alerts)
+	}
+}
+
+func TestEnvOverridesMaps(t *testing.T) {
+	cfg := Default()
+	cfg.Server.ToolTimeouts = map[string]time.Duration{"read_emails": time.Minute}
+
+	err := cfg.envOverrides([]string{
+		"SAPPHIREDUCK_SERVER_TOOL_TIMEOUTS_SEND_EMAIL=5s",
+		"SAPPHIREDUCK_SERVER_TOOL_TIMEOUTS_READ_EMAILS=5m",
+	})
+	if err != nil {
+		t.Fatalf("envOverrides: %v", err)
+	}
+	want := map[string]time.Duration{"send_email": 5 * time.Second, "read_emails": 5 * time.Minute}
+	if len(cfg.Server.ToolTimeouts) != len(want) {
+		t.Fatalf("ToolTimeouts = %v, want %v", cfg.Server.ToolTimeouts, want)
+	}
+	for tool, timeout := range want {
+		if cfg.Server.ToolTimeouts[tool] != timeout {
+			t.Errorf("ToolTimeouts[%s] = %v, want %v", tool, cfg.Server.ToolTimeouts[tool], timeout)
+		}
+	}
+
+	err = Default().envOverrides([]string{"SAPPHIREDUCK_SERVER_TOOL_TIMEOUTS_SEND_EMAIL=soon"})
+	var problems ValidationErrors
+	if !errors.As(err, &problems) || len(problems) != 1 || problems[0].Path != "SAPPHIREDUCK_SERVER_TOOL_TIMEOUTS_SEND_EMAIL" {
+		t.Errorf("expected the invalid map entry to be reported, got %v", err)
+	}
+}
+
+func TestEnvOverridesReportTypedErrors(t *testing.T) {
+	cfg := Default()
+	err := cfg.envOverrides([]string{
+		"SAPPHIREDUCK_SERVER_WATCH_INTERVAL=soon",
+		"SAPPHIREDUCK_APPROVAL_ELICIT=maybe",
+		"MCP_PORT=http",
+	})
+
+	var problems ValidationErrors
+	if !errors.As(err, &problems) || len(problems) != 3 {
+		t.Fatalf("expected three problems, got %v", err)
+	}
+	want := []string{"SAPPHIREDUCK_APPROVAL_ELICIT", "SAPPHIREDUCK_SERVER_PORT", "SAPPHIREDUCK_SERVER_WATCH_INTERVAL"}
+	for i, p := range problems {
+		if p.Path != want[i] {
+			t.Errorf("problem %d is for %s, want %s", i, p.Path, want[i])
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"ai-presence-mcp/internal/secrets"
+	"ai-presence-mcp/internal/vault"
+	"ai-presence-mcp/pkg/types"
+)
+
+// secretField is a configuration value that may hold a secret reference
+type secretField struct {
+	path  string
+	value *string
+}
+
+// secretFields lists the secret-bearing fields of the given accounts
+func secretFields(accounts []types.EmailConfig) []secretField {
+	var fields []secretField
+	for i := range accounts {
+		fields = append(fields, secretField{fmt.Sprintf("email[%d].password", i), &accounts[i].Password})
+		if accounts[i].OAuth != nil {
+			fields = append(fields, secretField{fmt.Sprintf("email[%d].oauth.client_secret", i), &accounts[i].OAuth.ClientSecret})
+		}
+	}
+	return fields
+}
+
+// serverSecretFields lists the secret-bearing fields outside the accounts.
+// References in them are resolved when the configuration is loaded.
+func (c *Config) serverSecretFields() []secretField {
+	return []secretField{
+		{"server.auth_token", &c.Server.AuthToken},
+		{"approval.http_token", &c.Approval.HTTPToken},
+	}
+}
+
+// Secrets returns the values of the configured secrets, so they can be kept
+// out of logs
+func (c *Config) Secrets() []string {
+	var values []string
+	for _, field := range append(secretFields(c.Email), c.serverSecretFields()...) {
+		if *field.value != "" {
+			values = append(values, *field.value)
+		}
+	}
+	return values
+}
+
+// resolveSecrets replaces secret references with their values, remembering
+// the references so RefreshSecrets can resolve them again
+func (c *Config) resolveSecrets() error {
+	c.secretRefs = make(map[string]string)
+
+	var errs ValidationErrors
+	for _, field := range append(secretFields(c.Email), c.serverSecretFields()...) {
+		if literal, ok := secrets.Literal(*field.value); ok {
+			*field.value = literal
+			continue
+		}
+		if !secrets.IsReference(*field.value) && !vault.IsReference(*field.value) {
+			if secrets.Ambiguous(*field.value) {
+				c.ambiguousSecrets = append(c.ambiguousSecrets, field.path)
+			}
+			continue
+		}
+		ref := *field.value
+		c.secretRefs[field.path] = ref
+
+		secret, err := c.resolve(ref)
+		if err != nil {
+			errs = append(errs, ValidationError{Path: field.path, Message: err.Error()})
+			continue
+		}
+		*field.value = secret
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to resolve secrets: %w", errs)
+	}
+	return nil
+}
+
+// resolve returns the secret ref points to. vault:NAME references read the
+// credential vault, which is unlocked on first use.
+func (c *Config) resolve(ref string) (string, error) {
+	name, ok := strings.CutPrefix(ref, vault.Prefix)
+	if !ok {
+		return secrets.Resolve(ref)
+	}
+	if c.vault == nil {
+		v, err := vault.Open(c.Vault)
+		if err != nil {
+			return "", err
+		}
+		c.vault = v
+	}
+	return c.vault.Get(name)
+}
+
+// HasSecretRefs reports whether any value was loaded from a secret reference
+func (c *Config) HasSecretRefs() bool {
+	return len(c.secretRefs) > 0
+}
+
+// RefreshSecrets resolves the secret references again. If any secret
+// changed, Email is replaced by an updated copy, leaving the previous slice
+// untouched for anyone still using it, and true is returned.
+func (c *Config) RefreshSecrets() (bool, error) {
+	if len(c.secretRefs) == 0 {
+		return false, nil
+	}
+
+	accounts := make([]types.EmailConfig, len(c.Email))
+	copy(accounts, c.Email)
+	for i := range accounts {
+		if accounts[i].OAuth != nil {
+			oauth := *accounts[i].OAuth
+			accounts[i].OAuth = &oauth
+		}
+	}
+
+	changed := false
+	for _, field := range secretFields(accounts) {
+		ref, ok := c.secretRefs[field.path]
+		if !ok {
+			continue
+		}
+		secret, err := c.resolve(ref)
+		if err != nil {
+			return false, fmt.Errorf("failed to refresh %s: %w", field.path, err)
+		}
+		if secret != *field.value {
+			*field.value = secret
+			changed = true
+		}
+	}
+
+	if changed {
+		c.Email = accounts
+	}
+	return changed, nil
+}
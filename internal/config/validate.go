@@ -0,0 +1,331 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"ai-presence-mcp/internal/discovery"
+	"ai-presence-mcp/internal/email"
+	"ai-presence-mcp/internal/oauth"
+	"ai-presence-mcp/internal/secrets"
+	"ai-presence-mcp/internal/vault"
+	"ai-presence-mcp/pkg/types"
+	"ai-presence-mcp/pkg/utils"
+)
+
+// ValidationError describes one invalid setting by its YAML path
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors collects every problem found by Validate
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d problems):\n  %s", len(e), strings.Join(lines, "\n  "))
+}
+
+// validator accumulates problems while walking the configuration
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) port(path string, port int) {
+	if port < 1 || port > 65535 {
+		v.add(path, "must be a port between 1 and 65535, got %d", port)
+	}
+}
+
+func (v *validator) required(path, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(path, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return
+		}
+	}
+	v.add(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) file(path, name string) {
+	if _, err := os.Stat(name); err != nil {
+		v.add(path, "cannot read %s: %v", name, err)
+	}
+}
+
+// Validate checks the configuration and returns a ValidationErrors listing
+// every problem, or nil if it is valid
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("server.port", c.Server.Port)
+	if !IsLoopback(c.Server.Host) && c.Server.AuthToken == "" {
+		v.add("server.auth_token", "is required when server.host %q is not a loopback address", c.Server.Host)
+	}
+	v.oneOf("server.log_level", c.Server.LogLevel, "debug", "info", "warn", "error")
+	if c.Server.SecretRefresh < 0 {
+		v.add("server.secret_refresh", "must not be negative")
+	}
+	if c.Discovery.Timeout < 0 {
+		v.add("discovery.timeout", "must not be negative")
+	}
+	if c.Discovery.ISPDBPath != "" {
+		v.file("discovery.ispdb_path", c.Discovery.ISPDBPath)
+	}
+	if c.Server.WatchInterval < 0 {
+		v.add("server.watch_interval", "must not be negative")
+	}
+	if c.Server.ToolTimeout < 0 {
+		v.add("server.tool_timeout", "must not be negative")
+	}
+	for tool, timeout := range c.Server.ToolTimeouts {
+		if timeout < 0 {
+			v.add("server.tool_timeouts."+tool, "must not be negative")
+		}
+	}
+	if c.Server.MailCheckInterval < 0 {
+		v.add("server.mail_check_interval", "must not be negative")
+	}
+
+	// Names and usernames share one namespace since either selects an account
+	seen := make(map[string]string)
+	defaults := 0
+	for i := range c.Email {
+		path := fmt.Sprintf("email[%d]", i)
+		account := &c.Email[i]
+		validateAccount(v, path, account)
+
+		key := strings.ToLower(account.Username)
+		if other, ok := seen[key]; ok && key != "" {
+			v.add(path+".username", "duplicates %s", other)
+		}
+		seen[key] = path + ".username"
+
+		if account.Name != "" {
+			if strings.ContainsAny(account.Name, " \t\n") {
+				v.add(path+".name", "must not contain whitespace")
+			}
+			key := strings.ToLower(account.Name)
+			if other, ok := seen[key]; ok && key != strings.ToLower(account.Username) {
+				v.add(path+".name", "duplicates %s", other)
+			}
+			seen[key] = path + ".name"
+		}
+		if account.Default {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		v.add("email", "only one account may be the default")
+	}
+
+	if c.Outbox.Path == "" {
+		v.add("outbox.path", "is required")
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		v.add("outbox.max_attempts", "must be at least 1")
+	}
+	if c.Outbox.RetryBaseDelay <= 0 {
+		v.add("outbox.retry_base_delay", "must be positive")
+	}
+	if c.Outbox.RetryMaxDelay < c.Outbox.RetryBaseDelay {
+		v.add("outbox.retry_max_delay", "must not be shorter than retry_base_delay")
+	}
+	if c.Outbox.PollInterval <= 0 {
+		v.add("outbox.poll_interval", "must be positive")
+	}
+
+	v.oneOf("approval.mode", c.Approval.Mode, "off", "required")
+	if c.Approval.Mode == "required" {
+		if c.Approval.TTL <= 0 {
+			v.add("approval.ttl", "must be positive")
+		}
+		if c.Approval.PollInterval <= 0 {
+			v.add("approval.poll_interval", "must be positive")
+		}
+		v.required("approval.path", c.Approval.Path)
+	}
+	if c.Approval.HTTPToken != "" && c.Approval.HTTPToken == c.Server.AuthToken {
+		v.add("approval.http_token", "must differ from server.auth_token")
+	}
+
+	if oauth.Configured(c.Email) {
+		v.required("tokens.path", c.Tokens.Path)
+		if os.Getenv(oauth.KeyEnv) == "" {
+			v.required("tokens.key_path", c.Tokens.KeyPath)
+		}
+	}
+
+	for _, ref := range c.secretRefs {
+		if vault.IsReference(ref) {
+			v.required("vault.path", c.Vault.Path)
+			break
+		}
+	}
+
+	// Not an error, since the value may well be the intended password
+	for _, path := range c.ambiguousSecrets {
+		log.Printf("Warning: %s looks like a secret reference but is used as written; prefix it with %q to say so",
+			path, secrets.LiteralPrefix)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func validateAccount(v *validator, path string, account *types.EmailConfig) {
+	v.required(path+".username", account.Username)
+	if account.Provider != "" && !discovery.KnownProvider(account.Provider) {
+		v.add(path+".provider", "must be %s or %s, got %q",
+			strings.Join(discovery.Providers(), ", "), discovery.Generic, account.Provider)
+	}
+	v.required(path+".imap_server", account.IMAPServer)
+	v.required(path+".smtp_server", account.SMTPServer)
+	v.port(path+".imap_port", account.IMAPPort)
+	v.port(path+".smtp_port", account.SMTPPort)
+
+	mech := strings.ToLower(account.Auth)
+	if mech != "" {
+		v.oneOf(path+".auth", mech, email.AuthPlain, email.AuthLogin, email.AuthCRAMMD5,
+			email.AuthSCRAMSHA1, email.AuthSCRAMSHA256, email.AuthXOAUTH2, email.AuthOAuthBearer)
+	}
+	oauthMech := mech == email.AuthXOAUTH2 || mech == email.AuthOAuthBearer
+	if account.OAuth == nil || !oauthMech {
+		v.required(path+".password", account.Password)
+	}
+
+	if o := account.OAuth; o != nil {
+		if !oauthMech {
+			v.add(path+".oauth", "requires auth to be xoauth2 or oauthbearer")
+		}
+		if o.Provider != "" && !oauth.KnownProvider(o.Provider) {
+			v.add(path+".oauth.provider", "must be google or microsoft, got %q", o.Provider)
+		}
+		if o.Provider == "" && (o.AuthURL == "" || o.TokenURL == "") {
+			v.add(path+".oauth", "needs a provider or both auth_url and token_url")
+		}
+		v.required(path+".oauth.client_id", o.ClientID)
+		if o.RedirectPort < 0 || o.RedirectPort > 65535 {
+			v.add(path+".oauth.redirect_port", "must be between 0 and 65535")
+		}
+	}
+
+	tls := account.TLS
+	modes := []string{email.TLSImplicit, email.TLSStartTLS, email.TLSNone}
+	if tls.Mode != "" {
+		v.oneOf(path+".tls.mode", tls.Mode, modes...)
+	}
+	if tls.IMAPMode != "" {
+		v.oneOf(path+".tls.imap_mode", tls.IMAPMode, modes...)
+	}
+	if tls.SMTPMode != "" {
+		v.oneOf(path+".tls.smtp_mode", tls.SMTPMode, modes...)
+	}
+	if tls.MinVersion != "" {
+		if _, err := email.ParseTLSVersion(tls.MinVersion); err != nil {
+			v.add(path+".tls.min_version", "must be 1.0, 1.1, 1.2 or 1.3, got %q", tls.MinVersion)
+		}
+	}
+	if tls.CAFile != "" {
+		v.file(path+".tls.ca_file", tls.CAFile)
+	}
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		v.add(path+".tls", "cert_file and key_file must be set together")
+	} else if tls.CertFile != "" {
+		v.file(path+".tls.cert_file", tls.CertFile)
+		v.file(path+".tls.key_file", tls.KeyFile)
+	}
+
+	if d := account.DKIM; d != nil {
+		v.required(path+".dkim.domain", d.Domain)
+		v.required(path+".dkim.selector", d.Selector)
+		if v.required(path+".dkim.private_key_path", d.PrivateKeyPath) {
+			v.file(path+".dkim.private_key_path", d.PrivateKeyPath)
+		}
+		if d.Algorithm != "" {
+			v.oneOf(path+".dkim.algorithm", d.Algorithm, "rsa", "ed25519")
+		}
+	}
+
+	defaults := 0
+	names := make(map[string]bool)
+	for j, id := range account.Identities {
+		idPath := fmt.Sprintf("%s.identities[%d]", path, j)
+		if v.required(idPath+".name", id.Name) {
+			if names[id.Name] {
+				v.add(idPath+".name", "duplicate identity %q", id.Name)
+			}
+			names[id.Name] = true
+		}
+		if id.From != "" {
+			if err := utils.ValidateEmail(id.From); err != nil {
+				v.add(idPath+".from", "%v", err)
+			}
+		}
+		if id.ReplyTo != "" {
+			if err := utils.ValidateEmail(id.ReplyTo); err != nil {
+				v.add(idPath+".reply_to", "%v", err)
+			}
+		}
+		if id.Default {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		v.add(path+".identities", "only one identity may be the default")
+	}
+
+	if account.Policy.MaxRecipients < 0 {
+		v.add(path+".policy.max_recipients", "must not be negative")
+	}
+	limits := account.Limits
+	for _, limit := range []struct {
+		field string
+		value float64
+	}{
+		{"send_per_minute", limits.SendPerMinute},
+		{"send_burst", float64(limits.SendBurst)},
+		{"daily_send_quota", float64(limits.DailySendQuota)},
+		{"domain_daily_quota", float64(limits.DomainDailyQuota)},
+		{"read_per_minute", limits.ReadPerMinute},
+		{"read_burst", float64(limits.ReadBurst)},
+	} {
+		if limit.value < 0 {
+			v.add(path+".limits."+limit.field, "must not be negative")
+		}
+	}
+}
+
+// IsLoopback reports whether host only accepts connections from this machine.
+// An empty host listens on every interface.
+func IsLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
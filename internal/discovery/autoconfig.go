@@ -0,0 +1,191 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ai-presence-mcp/internal/email"
+)
+
+// ispdbURL is the Thunderbird ISPDB, queried by domain
+const ispdbURL = "https://autoconfig.thunderbird.net/v1.1/"
+
+// maxConfigSize bounds the autoconfig documents read from the network
+const maxConfigSize = 1 << 20
+
+// clientConfig is a Mozilla autoconfig document, the format also used by
+// the ISPDB
+type clientConfig struct {
+	XMLName       xml.Name `xml:"clientConfig"`
+	EmailProvider struct {
+		Domains  []string       `xml:"domain"`
+		Incoming []serverConfig `xml:"incomingServer"`
+		Outgoing []serverConfig `xml:"outgoingServer"`
+	} `xml:"emailProvider"`
+}
+
+type serverConfig struct {
+	Type       string `xml:"type,attr"`
+	Hostname   string `xml:"hostname"`
+	Port       int    `xml:"port"`
+	SocketType string `xml:"socketType"`
+}
+
+// parseAutoconfig extracts IMAP and SMTP settings for address from an
+// autoconfig document. Servers without TLS are skipped.
+func parseAutoconfig(data []byte, address, source string) (*Settings, error) {
+	var doc clientConfig
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse autoconfig from %s: %w", source, err)
+	}
+
+	s := &Settings{Source: source}
+	for _, server := range doc.EmailProvider.Incoming {
+		if mode := socketMode(server.SocketType); server.Type == "imap" && mode != "" {
+			s.IMAPServer, s.IMAPPort, s.IMAPTLS = expand(server.Hostname, address), server.Port, mode
+			break
+		}
+	}
+	for _, server := range doc.EmailProvider.Outgoing {
+		if mode := socketMode(server.SocketType); server.Type == "smtp" && mode != "" {
+			s.SMTPServer, s.SMTPPort, s.SMTPTLS = expand(server.Hostname, address), server.Port, mode
+			break
+		}
+	}
+
+	if s.IMAPServer == "" || s.SMTPServer == "" {
+		return nil, fmt.Errorf("autoconfig from %s lists no IMAP and SMTP servers with TLS", source)
+	}
+	return s, nil
+}
+
+// socketMode maps an autoconfig socketType to a TLS mode, or "" for plain
+func socketMode(socketType string) string {
+	switch strings.ToUpper(socketType) {
+	case "SSL", "TLS":
+		return email.TLSImplicit
+	case "STARTTLS":
+		return email.TLSStartTLS
+	}
+	return ""
+}
+
+// expand substitutes the placeholders autoconfig documents may use
+func expand(value, address string) string {
+	local, domain, _ := strings.Cut(address, "@")
+	return strings.NewReplacer(
+		"%EMAILADDRESS%", address,
+		"%EMAILLOCALPART%", local,
+		"%EMAILDOMAIN%", domain,
+	).Replace(value)
+}
+
+// hasDomain reports whether an ISPDB document covers domain
+func hasDomain(data []byte, domain string) bool {
+	var doc clientConfig
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	for _, d := range doc.EmailProvider.Domains {
+		if strings.EqualFold(strings.TrimSpace(d), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// localISPDB looks domain up in a local copy of the ISPDB: either a
+// directory of documents named after their domain, as in the ISPDB
+// repository, or a single document
+func (d *Discoverer) localISPDB(address, domain string) (*Settings, error) {
+	info, err := os.Stat(d.ISPDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ISPDB: %w", err)
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(d.ISPDBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ISPDB: %w", err)
+		}
+		if !hasDomain(data, domain) {
+			return nil, errNotFound
+		}
+		return parseAutoconfig(data, address, d.ISPDBPath)
+	}
+
+	for _, name := range []string{domain, domain + ".xml"} {
+		path := filepath.Join(d.ISPDBPath, name)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ISPDB: %w", err)
+		}
+		return parseAutoconfig(data, address, path)
+	}
+	return nil, errNotFound
+}
+
+// autoconfigURLs lists the locations a domain may publish its autoconfig
+// document at, followed by the online ISPDB
+func autoconfigURLs(address, domain string) []string {
+	query := "?emailaddress=" + url.QueryEscape(address)
+	return []string{
+		"https://autoconfig." + domain + "/mail/config-v1.1.xml" + query,
+		"https://" + domain + "/.well-known/autoconfig/mail/config-v1.1.xml",
+		ispdbURL + domain,
+	}
+}
+
+// fetchAutoconfig tries each autoconfig URL for the address in turn
+func (d *Discoverer) fetchAutoconfig(ctx context.Context, address, domain string) (*Settings, error) {
+	var errs []error
+	for _, u := range d.autoconfigURLs(address, domain) {
+		data, err := d.fetch(ctx, u)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s, err := parseAutoconfig(data, address, u)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return s, nil
+	}
+	if len(errs) == 0 {
+		return nil, errNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (d *Discoverer) fetch(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", u, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/pkg/utils"
+)
+
+// Cache remembers discovered settings by address in a JSON file, so an
+// account's servers are looked up over the network once rather than every
+// time the configuration is loaded. A nil Cache remembers nothing.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	Settings     Settings  `json:"settings"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+}
+
+// OpenCache loads the cache stored at path, which is created on first use
+func OpenCache(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discovery cache: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &c.entries); err != nil {
+			return nil, fmt.Errorf("failed to parse discovery cache %s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// Get returns the settings cached for address
+func (c *Cache) Get(address string) (*Settings, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[strings.ToLower(address)]
+	if !ok {
+		return nil, false
+	}
+	settings := entry.Settings
+	return &settings, true
+}
+
+// Put caches the settings of address, replacing any cached before
+func (c *Cache) Put(address string, settings *Settings) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[strings.ToLower(address)] = cacheEntry{Settings: *settings, DiscoveredAt: time.Now().UTC()}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode discovery cache: %w", err)
+	}
+	if err := utils.WriteFileAtomic(c.path, data); err != nil {
+		return fmt.Errorf("failed to write discovery cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// Package discovery finds the IMAP and SMTP servers of an email address from
+// built-in provider presets, the ISPDB, Mozilla autoconfig and RFC 6186 SRV
+// records
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-presence-mcp/internal/email"
+	"ai-presence-mcp/pkg/types"
+)
+
+// Settings are the discovered servers of an account
+type Settings struct {
+	IMAPServer string `json:"imap_server"`
+	IMAPPort   int    `json:"imap_port"`
+	IMAPTLS    string `json:"imap_tls"`
+	SMTPServer string `json:"smtp_server"`
+	SMTPPort   int    `json:"smtp_port"`
+	SMTPTLS    string `json:"smtp_tls"`
+	// Source describes where the settings were found
+	Source string `json:"source"`
+	// Unverified is set when SRV records name a server outside the
+	// address's domain. Unauthenticated DNS could be pointing the password
+	// at an attacker, so such settings are only shown, never applied.
+	Unverified bool `json:"unverified,omitempty"`
+}
+
+// Resolver is the subset of net.Resolver used for DNS lookups
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// errNotFound is returned by a discovery method that has nothing for a domain
+var errNotFound = errors.New("not found")
+
+// Discoverer looks up mail server settings
+type Discoverer struct {
+	// ISPDBPath is a local ISPDB directory or document, consulted first
+	ISPDBPath string
+	// Offline disables autoconfig, DNS and online ISPDB lookups
+	Offline bool
+	// Timeout bounds the network lookups for one address
+	Timeout time.Duration
+
+	HTTPClient *http.Client
+	Resolver   Resolver
+	// AutoconfigURLs overrides the autoconfig locations, for tests
+	AutoconfigURLs func(address, domain string) []string
+}
+
+// New returns a Discoverer configured by config
+func New(config types.DiscoveryConfig) *Discoverer {
+	return &Discoverer{
+		ISPDBPath: config.ISPDBPath,
+		Offline:   config.Offline,
+		Timeout:   config.Timeout,
+	}
+}
+
+// Discover returns the settings for address, trying in order its domain's
+// provider preset, the local ISPDB, autoconfig (including the online ISPDB),
+// RFC 6186 SRV records and finally a preset matching the domain's MX hosts
+func (d *Discoverer) Discover(ctx context.Context, address string) (*Settings, error) {
+	_, domain, ok := strings.Cut(address, "@")
+	if !ok || domain == "" {
+		return nil, fmt.Errorf("cannot discover servers for %q: not an email address", address)
+	}
+	domain = strings.ToLower(domain)
+
+	if s, ok := presetForDomain(domain); ok {
+		return s, nil
+	}
+
+	var errs []error
+	if d.ISPDBPath != "" {
+		s, err := d.localISPDB(address, domain)
+		if err == nil {
+			return s, nil
+		}
+		if !errors.Is(err, errNotFound) {
+			errs = append(errs, err)
+		}
+	}
+
+	if d.Offline {
+		return nil, discoveryFailed(address, errs)
+	}
+
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+
+	s, err := d.fetchAutoconfig(ctx, address, domain)
+	if err == nil {
+		return s, nil
+	}
+	if !errors.Is(err, errNotFound) {
+		errs = append(errs, err)
+	}
+
+	srv, err := d.lookupSRV(ctx, domain)
+	if err == nil && !srv.Unverified {
+		return srv, nil
+	}
+	if err != nil && !errors.Is(err, errNotFound) {
+		errs = append(errs, err)
+	}
+
+	if mxs, err := d.resolver().LookupMX(ctx, domain); err == nil {
+		for _, mx := range mxs {
+			if s, ok := presetForMX(mx.Host); ok {
+				s.Source += " (MX " + strings.TrimSuffix(mx.Host, ".") + ")"
+				return s, nil
+			}
+		}
+	}
+
+	// SRV records pointing elsewhere are still worth showing to a human
+	if srv != nil {
+		return srv, nil
+	}
+	return nil, discoveryFailed(address, errs)
+}
+
+func discoveryFailed(address string, errs []error) error {
+	if len(errs) == 0 {
+		return fmt.Errorf("no mail server settings found for %s", address)
+	}
+	return fmt.Errorf("no mail server settings found for %s: %w", address, errors.Join(errs...))
+}
+
+// lookupSRV finds the servers advertised by RFC 6186 records, preferring
+// implicit TLS (RFC 8314) over STARTTLS. As RFC 6186 section 6 advises,
+// servers outside domain mark the settings Unverified.
+func (d *Discoverer) lookupSRV(ctx context.Context, domain string) (*Settings, error) {
+	s := &Settings{Source: "SRV records of " + domain}
+
+	var err error
+	s.IMAPServer, s.IMAPPort, s.IMAPTLS, err = d.srv(ctx, domain, "imaps", "imap")
+	if err != nil {
+		return nil, err
+	}
+	s.SMTPServer, s.SMTPPort, s.SMTPTLS, err = d.srv(ctx, domain, "submissions", "submission")
+	if err != nil {
+		return nil, err
+	}
+	s.Unverified = !InDomain(s.IMAPServer, domain) || !InDomain(s.SMTPServer, domain)
+	return s, nil
+}
+
+// InDomain reports whether host is domain or one of its subdomains
+func InDomain(host, domain string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// srv returns the best target of the implicit TLS service, or else of the
+// STARTTLS one. A target of "." means the service is not offered.
+func (d *Discoverer) srv(ctx context.Context, domain, implicit, starttls string) (string, int, string, error) {
+	for _, service := range []struct {
+		name, mode string
+	}{
+		{implicit, email.TLSImplicit},
+		{starttls, email.TLSStartTLS},
+	} {
+		_, records, err := d.resolver().LookupSRV(ctx, service.name, "tcp", domain)
+		if err != nil || len(records) == 0 {
+			continue
+		}
+		// LookupSRV sorts by priority and randomizes by weight
+		target := strings.TrimSuffix(records[0].Target, ".")
+		if target == "" {
+			continue
+		}
+		return target, int(records[0].Port), service.mode, nil
+	}
+	return "", 0, "", errNotFound
+}
+
+// Apply fills the servers and ports account leaves unset. The discovered TLS
+// mode is used for a server whose host and port were both discovered, unless
+// the account configures TLS itself.
+func Apply(account *types.EmailConfig, s *Settings) {
+	ownTLS := account.TLS.Mode != "" || account.UseTLS != nil
+
+	if account.IMAPServer == "" && account.IMAPPort == 0 && !ownTLS && account.TLS.IMAPMode == "" {
+		account.TLS.IMAPMode = s.IMAPTLS
+	}
+	if account.IMAPServer == "" {
+		account.IMAPServer = s.IMAPServer
+	}
+	if account.IMAPPort == 0 {
+		account.IMAPPort = s.IMAPPort
+	}
+
+	if account.SMTPServer == "" && account.SMTPPort == 0 && !ownTLS && account.TLS.SMTPMode == "" {
+		account.TLS.SMTPMode = s.SMTPTLS
+	}
+	if account.SMTPServer == "" {
+		account.SMTPServer = s.SMTPServer
+	}
+	if account.SMTPPort == 0 {
+		account.SMTPPort = s.SMTPPort
+	}
+}
+
+// Complete reports whether account already names its servers and ports
+func Complete(account *types.EmailConfig) bool {
+	return account.IMAPServer != "" && account.IMAPPort != 0 &&
+		account.SMTPServer != "" && account.SMTPPort != 0
+}
+
+func (d *Discoverer) client() *http.Client {
+	if d.HTTPClient != nil {
+		return d.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (d *Discoverer) resolver() Resolver {
+	if d.Resolver != nil {
+		return d.Resolver
+	}
+	return net.DefaultResolver
+}
+
+func (d *Discoverer) autoconfigURLs(address, domain string) []string {
+	if d.AutoconfigURLs != nil {
+		return d.AutoconfigURLs(address, domain)
+	}
+	return autoconfigURLs(address, domain)
+}
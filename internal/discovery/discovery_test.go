@@ -0,0 +1,225 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai-presence-mcp/internal/email"
+	"ai-presence-mcp/pkg/types"
+)
+
+const exampleConfig = `<?xml version="1.0"?>
+<clientConfig version="1.1">
+  <emailProvider id="example.com">
+    <domain>example.com</domain>
+    <incomingServer type="pop3">
+      <hostname>pop.example.com</hostname>
+      <port>995</port>
+      <socketType>SSL</socketType>
+    </incomingServer>
+    <incomingServer type="imap">
+      <hostname>plain.example.com</hostname>
+      <port>143</port>
+      <socketType>plain</socketType>
+    </incomingServer>
+    <incomingServer type="imap">
+      <hostname>imap.%EMAILDOMAIN%</hostname>
+      <port>993</port>
+      <socketType>SSL</socketType>
+    </incomingServer>
+    <outgoingServer type="smtp">
+      <hostname>smtp.example.com</hostname>
+      <port>587</port>
+      <socketType>STARTTLS</socketType>
+    </outgoingServer>
+  </emailProvider>
+</clientConfig>`
+
+// fakeResolver answers DNS lookups from maps, failing everything else
+type fakeResolver struct {
+	srv map[string]*net.SRV
+	mx  map[string]string
+}
+
+func (r fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if rec, ok := r.srv[service+"."+name]; ok {
+		return "", []*net.SRV{rec}, nil
+	}
+	return "", nil, errors.New("no such host")
+}
+
+func (r fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if host, ok := r.mx[name]; ok {
+		return []*net.MX{{Host: host}}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func checkSettings(t *testing.T, s *Settings, imap string, imapPort int, imapTLS, smtp string, smtpPort int, smtpTLS string) {
+	t.Helper()
+	if s.IMAPServer != imap || s.IMAPPort != imapPort || s.IMAPTLS != imapTLS ||
+		s.SMTPServer != smtp || s.SMTPPort != smtpPort || s.SMTPTLS != smtpTLS {
+		t.Errorf("unexpected settings from %s: %+v", s.Source, s)
+	}
+}
+
+func TestDiscoverPresetDomain(t *testing.T) {
+	d := &Discoverer{Offline: true}
+	s, err := d.Discover(context.Background(), "someone@Gmail.com")
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	checkSettings(t, s, "imap.gmail.com", 993, email.TLSImplicit, "smtp.gmail.com", 465, email.TLSImplicit)
+}
+
+func TestDiscoverLocalISPDB(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "example.com"), []byte(exampleConfig), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	for _, path := range []string{dir, filepath.Join(dir, "example.com")} {
+		d := &Discoverer{ISPDBPath: path, Offline: true}
+		s, err := d.Discover(context.Background(), "agent@example.com")
+		if err != nil {
+			t.Fatalf("Discover with %s: %v", path, err)
+		}
+		checkSettings(t, s, "imap.example.com", 993, email.TLSImplicit, "smtp.example.com", 587, email.TLSStartTLS)
+	}
+
+	d := &Discoverer{ISPDBPath: dir, Offline: true}
+	if _, err := d.Discover(context.Background(), "agent@example.org"); err == nil {
+		t.Error("expected an unknown domain to fail offline")
+	}
+}
+
+func TestDiscoverAutoconfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mail/config-v1.1.xml" || r.URL.Query().Get("emailaddress") != "agent@example.com" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(exampleConfig))
+	}))
+	defer server.Close()
+
+	d := &Discoverer{
+		Resolver: fakeResolver{},
+		AutoconfigURLs: func(address, domain string) []string {
+			return []string{server.URL + "/missing", server.URL + "/mail/config-v1.1.xml?emailaddress=" + address}
+		},
+	}
+	s, err := d.Discover(context.Background(), "agent@example.com")
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	checkSettings(t, s, "imap.example.com", 993, email.TLSImplicit, "smtp.example.com", 587, email.TLSStartTLS)
+}
+
+func TestDiscoverSRVAndMX(t *testing.T) {
+	noAutoconfig := func(address, domain string) []string { return nil }
+
+	d := &Discoverer{
+		AutoconfigURLs: noAutoconfig,
+		Resolver: fakeResolver{srv: map[string]*net.SRV{
+			"imaps.example.com":       {Target: "mail.example.com.", Port: 993},
+			"submissions.example.com": {Target: ".", Port: 0},
+			"submission.example.com":  {Target: "mail.example.com.", Port: 587},
+		}},
+	}
+	s, err := d.Discover(context.Background(), "agent@example.com")
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	checkSettings(t, s, "mail.example.com", 993, email.TLSImplicit, "mail.example.com", 587, email.TLSStartTLS)
+
+	d = &Discoverer{
+		AutoconfigURLs: noAutoconfig,
+		Resolver:       fakeResolver{mx: map[string]string{"example.com": "aspmx.l.google.com."}},
+	}
+	s, err = d.Discover(context.Background(), "agent@example.com")
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	checkSettings(t, s, "imap.gmail.com", 993, email.TLSImplicit, "smtp.gmail.com", 465, email.TLSImplicit)
+}
+
+func TestDiscoverSRVOutsideDomain(t *testing.T) {
+	d := &Discoverer{
+		AutoconfigURLs: func(address, domain string) []string { return nil },
+		Resolver: fakeResolver{srv: map[string]*net.SRV{
+			"imaps.example.com":       {Target: "imap.example.com.", Port: 993},
+			"submissions.example.com": {Target: "mail.attacker.net.", Port: 465},
+		}},
+	}
+	s, err := d.Discover(context.Background(), "agent@example.com")
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if !s.Unverified {
+		t.Errorf("expected an SRV target outside example.com to be unverified, got %+v", s)
+	}
+
+	for host, want := range map[string]bool{
+		"example.com":          true,
+		"IMAP.Example.com.":    true,
+		"notexample.com":       false,
+		"example.com.evil.net": false,
+	} {
+		if got := InDomain(host, "example.com"); got != want {
+			t.Errorf("InDomain(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestApplyKeepsExplicitSettings(t *testing.T) {
+	s, _ := Preset("outlook")
+	account := &types.EmailConfig{SMTPServer: "relay.example.com", IMAPPort: 143}
+	Apply(account, s)
+
+	if account.IMAPServer != "outlook.office365.com" || account.IMAPPort != 143 || account.TLS.IMAPMode != "" {
+		t.Errorf("expected the explicit IMAP port to be kept without a discovered TLS mode, got %+v", account)
+	}
+	if account.SMTPServer != "relay.example.com" || account.SMTPPort != 587 || account.TLS.SMTPMode != "" {
+		t.Errorf("expected the explicit SMTP server to be kept, got %+v", account)
+	}
+	if !Complete(account) {
+		t.Error("expected the account to be complete")
+	}
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discovery.json")
+	cache, err := OpenCache(path)
+	if err != nil {
+		t.Fatalf("OpenCache: %v", err)
+	}
+	if _, ok := cache.Get("agent@example.org"); ok {
+		t.Fatal("expected an empty cache")
+	}
+
+	settings := &Settings{IMAPServer: "imap.example.org", IMAPPort: 993, SMTPServer: "smtp.example.org", SMTPPort: 465, Source: "autoconfig"}
+	if err := cache.Put("Agent@Example.org", settings); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reopened, err := OpenCache(path)
+	if err != nil {
+		t.Fatalf("OpenCache: %v", err)
+	}
+	got, ok := reopened.Get("agent@example.org")
+	if !ok || *got != *settings {
+		t.Errorf("expected the cached settings after reopening, got %+v", got)
+	}
+
+	var disabled *Cache
+	if err := disabled.Put("agent@example.org", settings); err != nil {
+		t.Errorf("expected a nil cache to ignore Put, got %v", err)
+	}
+}
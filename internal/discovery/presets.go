@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"sort"
+	"strings"
+
+	"ai-presence-mcp/internal/email"
+)
+
+// preset is the server configuration of a well-known mail provider
+type preset struct {
+	settings Settings
+	// domains are the address domains the provider hosts
+	domains []string
+	// mxSuffixes identify custom domains hosted by the provider from their MX records
+	mxSuffixes []string
+}
+
+var presets = map[string]preset{
+	"gmail": {
+		settings: Settings{
+			IMAPServer: "imap.gmail.com", IMAPPort: 993, IMAPTLS: email.TLSImplicit,
+			SMTPServer: "smtp.gmail.com", SMTPPort: 465, SMTPTLS: email.TLSImplicit,
+		},
+		domains:    []string{"gmail.com", "googlemail.com"},
+		mxSuffixes: []string{"google.com", "googlemail.com"},
+	},
+	"outlook": {
+		settings: Settings{
+			IMAPServer: "outlook.office365.com", IMAPPort: 993, IMAPTLS: email.TLSImplicit,
+			SMTPServer: "smtp.office365.com", SMTPPort: 587, SMTPTLS: email.TLSStartTLS,
+		},
+		domains:    []string{"outlook.com", "hotmail.com", "live.com", "msn.com"},
+		mxSuffixes: []string{"outlook.com"},
+	},
+	"fastmail": {
+		settings: Settings{
+			IMAPServer: "imap.fastmail.com", IMAPPort: 993, IMAPTLS: email.TLSImplicit,
+			SMTPServer: "smtp.fastmail.com", SMTPPort: 465, SMTPTLS: email.TLSImplicit,
+		},
+		domains:    []string{"fastmail.com", "fastmail.fm"},
+		mxSuffixes: []string{"messagingengine.com"},
+	},
+	"icloud": {
+		settings: Settings{
+			IMAPServer: "imap.mail.me.com", IMAPPort: 993, IMAPTLS: email.TLSImplicit,
+			SMTPServer: "smtp.mail.me.com", SMTPPort: 587, SMTPTLS: email.TLSStartTLS,
+		},
+		domains:    []string{"icloud.com", "me.com", "mac.com"},
+		mxSuffixes: []string{"mail.icloud.com"},
+	},
+	"yahoo": {
+		settings: Settings{
+			IMAPServer: "imap.mail.yahoo.com", IMAPPort: 993, IMAPTLS: email.TLSImplicit,
+			SMTPServer: "smtp.mail.yahoo.com", SMTPPort: 465, SMTPTLS: email.TLSImplicit,
+		},
+		domains:    []string{"yahoo.com", "ymail.com"},
+		mxSuffixes: []string{"yahoodns.net"},
+	},
+	"purelymail": {
+		settings: Settings{
+			IMAPServer: "imap.purelymail.com", IMAPPort: 993, IMAPTLS: email.TLSImplicit,
+			SMTPServer: "smtp.purelymail.com", SMTPPort: 465, SMTPTLS: email.TLSImplicit,
+		},
+		domains:    []string{"purelymail.com"},
+		mxSuffixes: []string{"purelymail.com"},
+	},
+	"zoho": {
+		settings: Settings{
+			IMAPServer: "imap.zoho.com", IMAPPort: 993, IMAPTLS: email.TLSImplicit,
+			SMTPServer: "smtp.zoho.com", SMTPPort: 465, SMTPTLS: email.TLSImplicit,
+		},
+		domains:    []string{"zoho.com", "zohomail.com"},
+		mxSuffixes: []string{"zoho.com"},
+	},
+	// Proton Mail is only reachable through the local Proton Mail Bridge
+	"protonmail": {
+		settings: Settings{
+			IMAPServer: "127.0.0.1", IMAPPort: 1143, IMAPTLS: email.TLSStartTLS,
+			SMTPServer: "127.0.0.1", SMTPPort: 1025, SMTPTLS: email.TLSStartTLS,
+		},
+		domains: []string{"proton.me", "protonmail.com", "pm.me"},
+	},
+}
+
+// Generic is the provider name for accounts configured entirely by hand or
+// by autodiscovery
+const Generic = "generic"
+
+// Providers returns the names of the built-in provider presets
+func Providers() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KnownProvider reports whether name is a preset or "generic"
+func KnownProvider(name string) bool {
+	name = strings.ToLower(name)
+	_, ok := presets[name]
+	return ok || name == Generic
+}
+
+// Preset returns the settings of the named provider preset
+func Preset(name string) (*Settings, bool) {
+	p, ok := presets[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	s := p.settings
+	s.Source = "preset " + strings.ToLower(name)
+	return &s, true
+}
+
+// presetForDomain returns the preset hosting addresses at domain
+func presetForDomain(domain string) (*Settings, bool) {
+	for name, p := range presets {
+		for _, d := range p.domains {
+			if d == domain {
+				return Preset(name)
+			}
+		}
+	}
+	return nil, false
+}
+
+// presetForMX returns the preset whose mail exchangers include host
+func presetForMX(host string) (*Settings, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for name, p := range presets {
+		for _, suffix := range p.mxSuffixes {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return Preset(name)
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,82 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// ListAccountsTool implements the MCP Tool interface for discovering the
+// configured email accounts
+type ListAccountsTool struct {
+	service *Service
+}
+
+func NewListAccountsTool(service *Service) *ListAccountsTool {
+	return &ListAccountsTool{service: service}
+}
+
+func (t *ListAccountsTool) Name() string {
+	return "list_accounts"
+}
+
+func (t *ListAccountsTool) Description() string {
+	return "List the configured email accounts with their names, addresses, send-as identities and whether they can read and send. Use an account's name or address as the account argument of the other email tools."
+}
+
+func (t *ListAccountsTool) InputSchema() interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *ListAccountsTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	accounts := t.service.Accounts()
+	if len(accounts) == 0 {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: "No email accounts are configured",
+			}},
+		}, nil
+	}
+
+	result := fmt.Sprintf("%d email accounts:\n\n", len(accounts))
+	for _, a := range accounts {
+		name := a.Name
+		if name == "" {
+			name = a.Username
+		}
+		result += fmt.Sprintf("Account: %s", name)
+		if a.Default {
+			result += " (default)"
+		}
+		result += "\n"
+		result += fmt.Sprintf("   Address: %s\n", a.Username)
+		if a.Description != "" {
+			result += fmt.Sprintf("   Description: %s\n", a.Description)
+		}
+		capabilities := []string{"read"}
+		if a.ReadOnly {
+			capabilities = []string{"read (read-only)"}
+		}
+		if a.CanSend {
+			capabilities = append(capabilities, "send")
+		}
+		result += fmt.Sprintf("   Can: %s\n", strings.Join(capabilities, ", "))
+		if len(a.Identities) > 0 {
+			result += fmt.Sprintf("   Send-as identities: %s\n", strings.Join(a.Identities, ", "))
+		}
+		result += "\n"
+	}
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: result,
+		}},
+	}, nil
+}
@@ -0,0 +1,121 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// AccountError is returned when a message cannot be sent from the account or
+// identity it names: it does not exist or may not send. Retrying cannot help
+// until the configuration changes.
+type AccountError struct {
+	Message string
+}
+
+func (e *AccountError) Error() string {
+	return e.Message
+}
+
+// FindAccount returns the account selected by account, which may be its
+// name or username. An empty account selects the default account: the one
+// marked default, or else the first.
+func FindAccount(accounts []types.EmailConfig, account string) (*types.EmailConfig, error) {
+	if account == "" {
+		for i := range accounts {
+			if accounts[i].Default {
+				return &accounts[i], nil
+			}
+		}
+		if len(accounts) > 0 {
+			return &accounts[0], nil
+		}
+		return nil, &AccountError{Message: "no email accounts are configured"}
+	}
+
+	for i := range accounts {
+		if accounts[i].Name != "" && strings.EqualFold(accounts[i].Name, account) {
+			return &accounts[i], nil
+		}
+	}
+	for i := range accounts {
+		if strings.EqualFold(accounts[i].Username, account) {
+			return &accounts[i], nil
+		}
+	}
+
+	return nil, &AccountError{Message: fmt.Sprintf("email account not found: %s", account)}
+}
+
+// AccountLabel returns the name an account is shown and logged under
+func AccountLabel(config *types.EmailConfig) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	return config.Username
+}
+
+// CanSend reports whether the account may send email
+func CanSend(config *types.EmailConfig) bool {
+	return !config.ReadOnly && !config.NoSend
+}
+
+// AnyCanSend reports whether at least one of accounts may send email
+func AnyCanSend(accounts []types.EmailConfig) bool {
+	for i := range accounts {
+		if CanSend(&accounts[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSendAllowed refuses to send from read-only and no-send accounts
+func checkSendAllowed(config *types.EmailConfig) error {
+	if CanSend(config) {
+		return nil
+	}
+	reason := "no_send"
+	if config.ReadOnly {
+		reason = "read_only"
+	}
+	return &AccountError{Message: fmt.Sprintf("account %s is not allowed to send email (%s)", AccountLabel(config), reason)}
+}
+
+// AccountInfo describes an account without any of its secrets
+type AccountInfo struct {
+	Name        string   `json:"name,omitempty"`
+	Username    string   `json:"username"`
+	Description string   `json:"description,omitempty"`
+	Default     bool     `json:"default"`
+	CanRead     bool     `json:"can_read"`
+	CanSend     bool     `json:"can_send"`
+	ReadOnly    bool     `json:"read_only"`
+	Identities  []string `json:"identities,omitempty"`
+}
+
+// Accounts describes every configured account
+func (s *Service) Accounts() []AccountInfo {
+	configs := s.accounts()
+	def, _ := FindAccount(configs, "")
+
+	infos := make([]AccountInfo, 0, len(configs))
+	for i := range configs {
+		config := &configs[i]
+		info := AccountInfo{
+			Name:        config.Name,
+			Username:    config.Username,
+			Description: config.Description,
+			Default:     config == def,
+			CanRead:     true,
+			CanSend:     CanSend(config),
+			ReadOnly:    config.ReadOnly,
+		}
+		for j := range config.Identities {
+			info.Identities = append(info.Identities, identityFrom(config, &config.Identities[j]))
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
@@ -0,0 +1,89 @@
+package email
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func accountsService() *Service {
+	return NewService([]types.EmailConfig{
+		{Name: "work", Username: "agent@example.com", Password: "secret-1", SMTPServer: "127.0.0.1", SMTPPort: 1},
+		{Name: "archive", Username: "archive@example.com", Password: "secret-2", ReadOnly: true, Default: true},
+		{Username: "alerts@example.com", Password: "secret-3", NoSend: true, Description: "Monitoring alerts"},
+	})
+}
+
+func TestFindAccount(t *testing.T) {
+	accounts := accountsService().accounts()
+
+	tests := []struct {
+		account, want string
+	}{
+		{"", "archive@example.com"},
+		{"work", "agent@example.com"},
+		{"WORK", "agent@example.com"},
+		{"Agent@Example.com", "agent@example.com"},
+		{"alerts@example.com", "alerts@example.com"},
+	}
+	for _, tt := range tests {
+		got, err := FindAccount(accounts, tt.account)
+		if err != nil {
+			t.Errorf("FindAccount(%q): %v", tt.account, err)
+			continue
+		}
+		if got.Username != tt.want {
+			t.Errorf("FindAccount(%q) = %s, want %s", tt.account, got.Username, tt.want)
+		}
+	}
+
+	if _, err := FindAccount(accounts, "missing"); err == nil {
+		t.Error("expected an unknown account to fail")
+	}
+	if got, _ := FindAccount(accounts[:1], ""); got.Username != "agent@example.com" {
+		t.Errorf("expected the first account to be the default without a default flag, got %s", got.Username)
+	}
+}
+
+func TestSendCapabilities(t *testing.T) {
+	service := accountsService()
+
+	if _, err := service.PreviewEmail("friend@example.net", "Hi", "Body", "work", ""); err != nil {
+		t.Errorf("expected the work account to send, got %v", err)
+	}
+	for _, account := range []string{"archive", "alerts@example.com"} {
+		err := service.SendEmail(context.Background(), "friend@example.net", "Hi", "Body", account, "")
+		if err == nil || !strings.Contains(err.Error(), "not allowed to send") {
+			t.Errorf("expected %s to be refused, got %v", account, err)
+		}
+	}
+}
+
+func TestAnyCanSend(t *testing.T) {
+	accounts := accountsService().accounts()
+	if !AnyCanSend(accounts) {
+		t.Error("expected the work account to send")
+	}
+	if AnyCanSend(accounts[1:]) {
+		t.Error("expected read-only and no-send accounts not to send")
+	}
+}
+
+func TestListAccountsHidesSecrets(t *testing.T) {
+	result, err := NewListAccountsTool(accountsService()).Execute(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	text := result.Content[0].Text
+
+	for _, want := range []string{"Account: work", "Account: archive (default)", "read (read-only)", "Monitoring alerts"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "secret-") {
+		t.Errorf("list_accounts leaks a password:\n%s", text)
+	}
+}
@@ -0,0 +1,209 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ai-presence-mcp/pkg/types"
+
+	"github.com/emersion/go-sasl"
+	gomail "github.com/wneessen/go-mail"
+	"github.com/wneessen/go-mail/smtp"
+)
+
+// Authentication mechanisms accepted in types.EmailConfig.Auth
+const (
+	AuthPlain       = "plain"
+	AuthLogin       = "login"
+	AuthCRAMMD5     = "cram-md5"
+	AuthSCRAMSHA1   = "scram-sha-1"
+	AuthSCRAMSHA256 = "scram-sha-256"
+	AuthXOAUTH2     = "xoauth2"
+	AuthOAuthBearer = "oauthbearer"
+)
+
+// authMechanism returns the normalized mechanism configured for an account.
+// An empty value keeps the historical behaviour: IMAP LOGIN and SMTP PLAIN.
+func authMechanism(config *types.EmailConfig) (string, error) {
+	mech := strings.ToLower(strings.TrimSpace(config.Auth))
+	switch mech {
+	case "", AuthPlain, AuthLogin, AuthCRAMMD5, AuthSCRAMSHA1, AuthSCRAMSHA256, AuthXOAUTH2, AuthOAuthBearer:
+		return mech, nil
+	default:
+		return "", fmt.Errorf("unsupported auth mechanism %q for account %s", config.Auth, config.Username)
+	}
+}
+
+// isOAuthMechanism reports whether mech authenticates with an access token
+func isOAuthMechanism(mech string) bool {
+	return mech == AuthXOAUTH2 || mech == AuthOAuthBearer
+}
+
+// TokenProvider supplies OAuth access tokens for accounts with an OAuth block
+type TokenProvider interface {
+	AccessToken(ctx context.Context, config *types.EmailConfig) (string, error)
+}
+
+// credential returns the secret presented for the account: the password, or
+// for OAuth mechanisms the access token. Accounts without an OAuth block use
+// their password as a static access token.
+func (s *Service) credential(ctx context.Context, config *types.EmailConfig) (string, error) {
+	mech, err := authMechanism(config)
+	if err != nil {
+		return "", err
+	}
+	if !isOAuthMechanism(mech) || config.OAuth == nil {
+		return config.Password, nil
+	}
+	s.mu.RLock()
+	tokens := s.tokens
+	s.mu.RUnlock()
+	if tokens == nil {
+		return "", fmt.Errorf("OAuth is configured for %s but no token provider is available", config.Username)
+	}
+	return tokens.AccessToken(ctx, config)
+}
+
+// imapAuth returns the SASL client used to authenticate an IMAP session, or
+// nil if the plain LOGIN command should be used
+func (s *Service) imapAuth(ctx context.Context, config *types.EmailConfig) (sasl.Client, error) {
+	mech, err := authMechanism(config)
+	if err != nil {
+		return nil, err
+	}
+	if mech == "" {
+		return nil, nil
+	}
+
+	secret, err := s.credential(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	// STARTTLS is required rather than opportunistic, so only mode none
+	// leaves the session unencrypted
+	mode, err := imapTLSMode(config)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := mode != TLSNone
+
+	switch mech {
+	case AuthPlain:
+		return sasl.NewPlainClient("", config.Username, secret), nil
+	case AuthLogin:
+		return sasl.NewLoginClient(config.Username, secret), nil
+	case AuthOAuthBearer:
+		return sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{
+			Username: config.Username,
+			Token:    secret,
+			Host:     config.IMAPServer,
+			Port:     config.IMAPPort,
+		}), nil
+	case AuthCRAMMD5:
+		return &smtpSASLClient{auth: smtp.CRAMMD5Auth(config.Username, secret), host: config.IMAPServer, tls: encrypted}, nil
+	case AuthSCRAMSHA1:
+		return &smtpSASLClient{auth: smtp.ScramSHA1Auth(config.Username, secret), host: config.IMAPServer, tls: encrypted}, nil
+	case AuthSCRAMSHA256:
+		return &smtpSASLClient{auth: smtp.ScramSHA256Auth(config.Username, secret), host: config.IMAPServer, tls: encrypted}, nil
+	default: // AuthXOAUTH2
+		return &smtpSASLClient{auth: smtp.XOAuth2Auth(config.Username, secret), host: config.IMAPServer, tls: encrypted}, nil
+	}
+}
+
+// smtpClientOptions returns the go-mail options that secure and
+// authenticate an SMTP session for the account
+func (s *Service) smtpClientOptions(ctx context.Context, config *types.EmailConfig) ([]gomail.Option, error) {
+	mech, err := authMechanism(config)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := smtpTLSOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	// go-mail refuses to send PLAIN and LOGIN credentials in the clear
+	// unless told the connection is deliberately unencrypted
+	mode, _ := smtpTLSMode(config)
+	plaintext := mode == TLSNone
+
+	secret, err := s.credential(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	options = append(options,
+		gomail.WithUsername(config.Username),
+		gomail.WithPassword(secret),
+	)
+
+	switch mech {
+	case "", AuthPlain:
+		if plaintext {
+			options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthPlainNoEnc))
+		} else {
+			options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthPlain))
+		}
+	case AuthLogin:
+		if plaintext {
+			options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthLoginNoEnc))
+		} else {
+			options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthLogin))
+		}
+	case AuthCRAMMD5:
+		options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthCramMD5))
+	case AuthSCRAMSHA1:
+		options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthSCRAMSHA1))
+	case AuthSCRAMSHA256:
+		options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthSCRAMSHA256))
+	case AuthXOAUTH2:
+		options = append(options, gomail.WithSMTPAuth(gomail.SMTPAuthXOAUTH2))
+	case AuthOAuthBearer:
+		// go-mail has no OAUTHBEARER support, so drive the go-sasl client
+		options = append(options, gomail.WithSMTPAuthCustom(&saslSMTPAuth{
+			client: sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{
+				Username: config.Username,
+				Token:    secret,
+				Host:     config.SMTPServer,
+				Port:     config.SMTPPort,
+			}),
+		}))
+	}
+
+	return options, nil
+}
+
+// smtpSASLClient adapts a go-mail SMTP mechanism to the SASL client
+// interface used by go-imap, for mechanisms go-sasl does not provide
+type smtpSASLClient struct {
+	auth smtp.Auth
+	host string
+	// tls tells the mechanism whether the session is encrypted, so ones
+	// that refuse to send credentials in the clear can do so
+	tls bool
+}
+
+func (c *smtpSASLClient) Start() (string, []byte, error) {
+	return c.auth.Start(&smtp.ServerInfo{Name: c.host, TLS: c.tls})
+}
+
+func (c *smtpSASLClient) Next(challenge []byte) ([]byte, error) {
+	return c.auth.Next(challenge, true)
+}
+
+// saslSMTPAuth adapts a go-sasl client to go-mail's SMTP auth interface
+type saslSMTPAuth struct {
+	client sasl.Client
+}
+
+func (a *saslSMTPAuth) Start(_ *smtp.ServerInfo) (string, []byte, error) {
+	return a.client.Start()
+}
+
+func (a *saslSMTPAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	return a.client.Next(fromServer)
+}
@@ -0,0 +1,171 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+
+	"github.com/wneessen/go-mail/smtp"
+)
+
+func TestIMAPAuthMechanisms(t *testing.T) {
+	service := NewService(nil)
+	config := &types.EmailConfig{
+		Username:   "agent@example.com",
+		Password:   "secret",
+		IMAPServer: "imap.example.com",
+		IMAPPort:   993,
+	}
+
+	tests := []struct {
+		auth, mech string
+	}{
+		{"", ""},
+		{"PLAIN", "PLAIN"},
+		{"login", "LOGIN"},
+		{"cram-md5", "CRAM-MD5"},
+		{"scram-sha-1", "SCRAM-SHA-1"},
+		{"scram-sha-256", "SCRAM-SHA-256"},
+		{"xoauth2", "XOAUTH2"},
+		{"oauthbearer", "OAUTHBEARER"},
+	}
+	for _, tt := range tests {
+		config.Auth = tt.auth
+		client, err := service.imapAuth(context.Background(), config)
+		if err != nil {
+			t.Fatalf("imapAuth(%q): %v", tt.auth, err)
+		}
+		if client == nil {
+			if tt.mech != "" {
+				t.Errorf("imapAuth(%q) returned no SASL client", tt.auth)
+			}
+			continue
+		}
+		mech, _, err := client.Start()
+		if err != nil {
+			t.Fatalf("Start(%q): %v", tt.auth, err)
+		}
+		if mech != tt.mech {
+			t.Errorf("imapAuth(%q) started %s, want %s", tt.auth, mech, tt.mech)
+		}
+	}
+
+	config.Auth = "kerberos"
+	if _, err := service.imapAuth(context.Background(), config); err == nil {
+		t.Error("expected an unknown mechanism to be rejected")
+	}
+}
+
+func TestIMAPCRAMMD5Response(t *testing.T) {
+	service := NewService(nil)
+	client, err := service.imapAuth(context.Background(), &types.EmailConfig{
+		Username:   "agent@example.com",
+		Password:   "secret",
+		IMAPServer: "imap.example.com",
+		Auth:       "cram-md5",
+	})
+	if err != nil {
+		t.Fatalf("imapAuth: %v", err)
+	}
+	if _, _, err := client.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	challenge := []byte("<1896.697170952@imap.example.com>")
+	resp, err := client.Next(challenge)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+
+	mac := hmac.New(md5.New, []byte("secret"))
+	mac.Write(challenge)
+	want := "agent@example.com " + hex.EncodeToString(mac.Sum(nil))
+	if string(resp) != want {
+		t.Errorf("CRAM-MD5 response = %q, want %q", resp, want)
+	}
+}
+
+// recordingAuth records the server info a mechanism is started with
+type recordingAuth struct {
+	server *smtp.ServerInfo
+}
+
+func (a *recordingAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	a.server = server
+	return "TEST", nil, nil
+}
+
+func (a *recordingAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	return nil, nil
+}
+
+func TestIMAPAuthReportsTLS(t *testing.T) {
+	service := NewService(nil)
+	tests := []struct {
+		mode string
+		port int
+		want bool
+	}{
+		{"", 993, true},
+		{TLSStartTLS, 143, true},
+		{TLSNone, 143, false},
+	}
+	for _, tt := range tests {
+		client, err := service.imapAuth(context.Background(), &types.EmailConfig{
+			Username:   "agent@example.com",
+			Password:   "secret",
+			IMAPServer: "imap.example.com",
+			IMAPPort:   tt.port,
+			Auth:       "cram-md5",
+			TLS:        types.TLSConfig{Mode: tt.mode},
+		})
+		if err != nil {
+			t.Fatalf("imapAuth(%q): %v", tt.mode, err)
+		}
+		adapter := client.(*smtpSASLClient)
+		recorder := &recordingAuth{}
+		adapter.auth = recorder
+		if _, _, err := adapter.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		if recorder.server.TLS != tt.want {
+			t.Errorf("mode %q on port %d: mechanism told TLS = %v, want %v", tt.mode, tt.port, recorder.server.TLS, tt.want)
+		}
+	}
+}
+
+func TestSMTPOAuthBearer(t *testing.T) {
+	service := NewService(nil)
+	config := &types.EmailConfig{
+		Username:   "agent@example.com",
+		Password:   "access-token",
+		SMTPServer: "smtp.example.com",
+		SMTPPort:   587,
+		Auth:       "oauthbearer",
+	}
+
+	if _, err := service.smtpClientOptions(context.Background(), config); err != nil {
+		t.Fatalf("smtpClientOptions: %v", err)
+	}
+
+	auth, err := service.imapAuth(context.Background(), config)
+	if err != nil {
+		t.Fatalf("imapAuth: %v", err)
+	}
+	adapter := &saslSMTPAuth{client: auth}
+	mech, ir, err := adapter.Start(nil)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if mech != "OAUTHBEARER" || !strings.Contains(string(ir), "auth=Bearer access-token") {
+		t.Errorf("unexpected OAUTHBEARER start %s %q", mech, ir)
+	}
+	if resp, err := adapter.Next([]byte("ignored"), false); resp != nil || err != nil {
+		t.Errorf("expected no response once the server is done, got %q, %v", resp, err)
+	}
+}
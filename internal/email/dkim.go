@@ -0,0 +1,160 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"ai-presence-mcp/pkg/types"
+
+	"github.com/emersion/go-msgauth/dkim"
+	gomail "github.com/wneessen/go-mail"
+)
+
+// defaultDKIMHeaders are signed when an account does not list its own,
+// following the recommendations in RFC 6376 section 5.4.1
+var defaultDKIMHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"In-Reply-To", "References", "MIME-Version", "Content-Type",
+}
+
+// signDKIM adds a DKIM-Signature header to m. The message is rendered once
+// to compute the signature; go-mail keeps its multipart boundaries, date and
+// message ID fixed from then on, so the bytes submitted over SMTP match the
+// signed ones.
+func signDKIM(m *gomail.Msg, config *types.DKIMConfig) error {
+	key, err := loadDKIMKey(config.PrivateKeyPath)
+	if err != nil {
+		return err
+	}
+	if err := checkDKIMAlgorithm(key, config.Algorithm); err != nil {
+		return err
+	}
+
+	headers := config.Headers
+	if len(headers) == 0 {
+		headers = defaultDKIMHeaders
+	}
+
+	signer, err := dkim.NewSigner(&dkim.SignOptions{
+		Domain:                 config.Domain,
+		Selector:               config.Selector,
+		Signer:                 key,
+		Hash:                   crypto.SHA256,
+		HeaderCanonicalization: dkim.CanonicalizationRelaxed,
+		BodyCanonicalization:   dkim.CanonicalizationRelaxed,
+		HeaderKeys:             headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create DKIM signer: %w", err)
+	}
+
+	if _, err := m.WriteTo(signer); err != nil {
+		signer.Close()
+		return fmt.Errorf("failed to render message for DKIM signing: %w", err)
+	}
+	if err := signer.Close(); err != nil {
+		return fmt.Errorf("failed to DKIM sign message: %w", err)
+	}
+
+	value := strings.TrimPrefix(signer.Signature(), "DKIM-Signature: ")
+	m.SetGenHeaderPreformatted("DKIM-Signature", strings.TrimSuffix(value, "\r\n"))
+	return nil
+}
+
+// loadDKIMKey reads a PEM encoded RSA (PKCS#1 or PKCS#8) or Ed25519
+// (PKCS#8) private key
+func loadDKIMKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DKIM private key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("DKIM private key %s is not PEM encoded", path)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DKIM private key: %w", err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DKIM private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported DKIM private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in DKIM private key", block.Type)
+	}
+}
+
+// checkDKIMAlgorithm ensures the key matches the configured algorithm, if any
+func checkDKIMAlgorithm(key crypto.Signer, algorithm string) error {
+	var actual string
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		actual = "rsa"
+	case ed25519.PublicKey:
+		actual = "ed25519"
+	default:
+		return fmt.Errorf("unsupported DKIM key type %T", key.Public())
+	}
+
+	if algorithm != "" && !strings.EqualFold(algorithm, actual) {
+		return fmt.Errorf("DKIM algorithm is %s but the private key is %s", algorithm, actual)
+	}
+	return nil
+}
+
+// DKIMPublicKeyRecord returns the DNS TXT record value to publish at
+// <selector>._domainkey.<domain> for the given signing key
+func DKIMPublicKeyRecord(key crypto.Signer) (string, error) {
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", err
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub), nil
+	default:
+		return "", fmt.Errorf("unsupported DKIM key type %T", pub)
+	}
+}
+
+// VerifyDKIM checks every DKIM signature in a raw message. lookupTXT
+// resolves the TXT records for a <selector>._domainkey.<domain> name; pass
+// nil to use DNS. It is mainly intended for tests and troubleshooting.
+func VerifyDKIM(source []byte, lookupTXT func(domain string) ([]string, error)) ([]*dkim.Verification, error) {
+	options := &dkim.VerifyOptions{LookupTXT: lookupTXT}
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(source), options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify DKIM signatures: %w", err)
+	}
+	if len(verifications) == 0 {
+		return nil, fmt.Errorf("message has no DKIM signature")
+	}
+	for _, v := range verifications {
+		if v.Err != nil {
+			return verifications, fmt.Errorf("DKIM signature for %s is invalid: %w", v.Domain, v.Err)
+		}
+	}
+	return verifications, nil
+}
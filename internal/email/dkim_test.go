@@ -0,0 +1,68 @@
+package email
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func TestPreviewEmailIsDKIMSigned(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "dkim.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	service := NewService([]types.EmailConfig{{
+		Username:   "agent@example.com",
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   1,
+		DKIM: &types.DKIMConfig{
+			Domain:         "example.com",
+			Selector:       "mcp",
+			PrivateKeyPath: keyPath,
+		},
+	}})
+
+	rendered, err := service.PreviewEmail("friend@example.org", "Signed", "Body text", "", "")
+	if err != nil {
+		t.Fatalf("PreviewEmail: %v", err)
+	}
+
+	record, err := DKIMPublicKeyRecord(key)
+	if err != nil {
+		t.Fatalf("DKIMPublicKeyRecord: %v", err)
+	}
+	lookup := func(domain string) ([]string, error) {
+		if domain != "mcp._domainkey.example.com" {
+			t.Errorf("unexpected DKIM lookup for %s", domain)
+		}
+		return []string{record}, nil
+	}
+
+	verifications, err := VerifyDKIM([]byte(rendered.Source), lookup)
+	if err != nil {
+		t.Fatalf("VerifyDKIM: %v\n%s", err, rendered.Source)
+	}
+	if verifications[0].Domain != "example.com" {
+		t.Errorf("unexpected signing domain %q", verifications[0].Domain)
+	}
+
+	service.configs[0].DKIM.Algorithm = "rsa"
+	if _, err := service.PreviewEmail("friend@example.org", "Signed", "Body text", "", ""); err == nil {
+		t.Error("expected a key/algorithm mismatch to fail")
+	}
+}
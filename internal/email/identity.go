@@ -0,0 +1,111 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"ai-presence-mcp/pkg/types"
+
+	gomail "github.com/wneessen/go-mail"
+)
+
+// resolveIdentity returns the identity of config named name, matching either
+// the identity's name or its from address. An empty name selects the
+// account's default identity, or the bare account if it has none.
+func resolveIdentity(config *types.EmailConfig, name string) (*types.Identity, error) {
+	if name == "" {
+		for i := range config.Identities {
+			if config.Identities[i].Default {
+				return &config.Identities[i], nil
+			}
+		}
+		return &types.Identity{From: config.Username}, nil
+	}
+
+	if strings.EqualFold(name, config.Username) {
+		for i := range config.Identities {
+			if identityFrom(config, &config.Identities[i]) == strings.ToLower(config.Username) {
+				return &config.Identities[i], nil
+			}
+		}
+		return &types.Identity{From: config.Username}, nil
+	}
+
+	for i := range config.Identities {
+		id := &config.Identities[i]
+		if id.Name == name || identityFrom(config, id) == strings.ToLower(name) {
+			return id, nil
+		}
+	}
+
+	return nil, &AccountError{Message: fmt.Sprintf("identity %s does not belong to account %s", name, config.Username)}
+}
+
+// identityFrom returns the lowercased from address of an identity
+func identityFrom(config *types.EmailConfig, id *types.Identity) string {
+	if id.From == "" {
+		return strings.ToLower(config.Username)
+	}
+	return strings.ToLower(id.From)
+}
+
+// ResolveSender maps a "from" address to the account that may send as it and
+// the identity to use. Addresses that match no account or identity are
+// returned as the account so the usual "account not found" error applies.
+func (s *Service) ResolveSender(from string) (account, identity string) {
+	configs := s.accounts()
+	for i := range configs {
+		if strings.EqualFold(configs[i].Username, from) {
+			return configs[i].Username, ""
+		}
+	}
+	for i := range configs {
+		config := &configs[i]
+		for j := range config.Identities {
+			if identityFrom(config, &config.Identities[j]) == strings.ToLower(from) {
+				return config.Username, from
+			}
+		}
+	}
+	return from, ""
+}
+
+// applyIdentity sets the From and Reply-To headers of m and adds the
+// identity's signature to the body. An HTML signature adds a text/html
+// alternative part.
+func applyIdentity(m *gomail.Msg, config *types.EmailConfig, id *types.Identity, body string) error {
+	from := id.From
+	if from == "" {
+		from = config.Username
+	}
+
+	if id.DisplayName != "" {
+		if err := m.FromFormat(id.DisplayName, from); err != nil {
+			return fmt.Errorf("failed to set sender: %w", err)
+		}
+	} else if err := m.From(from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+
+	if id.ReplyTo != "" {
+		if err := m.ReplyTo(id.ReplyTo); err != nil {
+			return fmt.Errorf("failed to set reply-to: %w", err)
+		}
+	}
+
+	plain := body
+	if id.Signature != "" {
+		// "-- " is the conventional signature separator (RFC 3676)
+		plain += "\n\n-- \n" + id.Signature
+	}
+	m.SetBodyString(gomail.TypeTextPlain, plain)
+
+	if id.HTMLSignature != "" {
+		htmlBody := strings.ReplaceAll(html.EscapeString(body), "\n", "<br>\n")
+		m.AddAlternativeString(gomail.TypeTextHTML,
+			"<html><body>"+htmlBody+"<br>\n<br>\n<div class=\"signature\">"+id.HTMLSignature+"</div></body></html>")
+	}
+
+	return nil
+}
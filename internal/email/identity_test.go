@@ -0,0 +1,88 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func identityService() *Service {
+	return NewService([]types.EmailConfig{
+		{
+			Username:   "agent@example.com",
+			SMTPServer: "127.0.0.1",
+			SMTPPort:   1,
+			Identities: []types.Identity{
+				{
+					Name:        "support",
+					From:        "support@example.com",
+					DisplayName: "Example Support",
+					ReplyTo:     "help@example.com",
+					Signature:   "The Support Team",
+				},
+				{
+					Name:          "personal",
+					DisplayName:   "Agent Smith",
+					HTMLSignature: "<b>Agent Smith</b>",
+					Default:       true,
+				},
+			},
+		},
+		{Username: "other@example.org", SMTPServer: "127.0.0.1", SMTPPort: 1},
+	})
+}
+
+func TestPreviewEmailWithIdentity(t *testing.T) {
+	service := identityService()
+
+	rendered, err := service.PreviewEmail("friend@example.net", "Hello", "Body text", "", "support")
+	if err != nil {
+		t.Fatalf("PreviewEmail: %v", err)
+	}
+	if rendered.From != "support@example.com" {
+		t.Errorf("unexpected envelope sender %q", rendered.From)
+	}
+	for _, want := range []string{
+		`From: "Example Support" <support@example.com>`,
+		"Reply-To: <help@example.com>",
+		"Body text\r\n\r\n--=20\r\nThe Support Team", // quoted-printable "-- "
+	} {
+		if !strings.Contains(rendered.Source, want) {
+			t.Errorf("rendered source is missing %q:\n%s", want, rendered.Source)
+		}
+	}
+
+	rendered, err = service.PreviewEmail("friend@example.net", "Hello", "Body text", "", "")
+	if err != nil {
+		t.Fatalf("PreviewEmail with default identity: %v", err)
+	}
+	for _, want := range []string{`From: "Agent Smith" <agent@example.com>`, "text/html", "<b>Agent Smith</b>"} {
+		if !strings.Contains(rendered.Source, want) {
+			t.Errorf("default identity source is missing %q:\n%s", want, rendered.Source)
+		}
+	}
+
+	if _, err := service.PreviewEmail("friend@example.net", "Hello", "Body", "other@example.org", "support"); err == nil {
+		t.Error("expected an identity of another account to be rejected")
+	}
+}
+
+func TestResolveSender(t *testing.T) {
+	service := identityService()
+
+	tests := []struct {
+		from, account, identity string
+	}{
+		{"agent@example.com", "agent@example.com", ""},
+		{"Support@Example.com", "agent@example.com", "Support@Example.com"},
+		{"other@example.org", "other@example.org", ""},
+		{"nobody@example.com", "nobody@example.com", ""},
+	}
+	for _, tt := range tests {
+		account, identity := service.ResolveSender(tt.from)
+		if account != tt.account || identity != tt.identity {
+			t.Errorf("ResolveSender(%q) = %q, %q; want %q, %q", tt.from, account, identity, tt.account, tt.identity)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package email
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/internal/ratelimit"
+	"ai-presence-mcp/pkg/types"
+)
+
+// quotaWindow is the rolling window for daily send quotas
+const quotaWindow = 24 * time.Hour
+
+// RateLimitError is returned when an account has exhausted a rate limit or
+// quota. The operation may be retried after RetryAfter.
+type RateLimitError struct {
+	Account    string
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for account %s: %s (retry in %s)",
+		e.Account, e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+// QuotaStatus reports the remaining capacity of an account
+type QuotaStatus struct {
+	Account            string  `json:"account"`
+	SendTokens         *int    `json:"send_tokens,omitempty"`
+	ReadTokens         *int    `json:"read_tokens,omitempty"`
+	DailySent          int     `json:"daily_sent"`
+	DailyQuota         int     `json:"daily_quota,omitempty"`
+	DailyRemaining     *int    `json:"daily_remaining,omitempty"`
+	DomainDailyQuota   int     `json:"domain_daily_quota,omitempty"`
+	QuotaResetsIn      string  `json:"quota_resets_in,omitempty"`
+	ReadRequestsPerMin float64 `json:"read_requests_per_minute,omitempty"`
+	SendMessagesPerMin float64 `json:"send_messages_per_minute,omitempty"`
+}
+
+// Limiter enforces the per-account limits in types.EmailConfig.Limits
+type Limiter struct {
+	store *ratelimit.Store
+
+	// mu makes checking the daily quotas and reserving a slot in them one
+	// step, so concurrent sends cannot both take the last slot
+	mu sync.Mutex
+}
+
+func NewLimiter(store *ratelimit.Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// checkSend returns a RateLimitError if sending to recipients now would
+// exceed the account's send rate or daily quotas. A passing check consumes
+// one token from the send rate bucket and reserves the message's slot in the
+// daily quotas, which releaseSend gives back if it is not delivered.
+func (l *Limiter) checkSend(config *types.EmailConfig, recipients []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits := config.Limits
+
+	if limits.DailySendQuota > 0 {
+		key := sendKey(config)
+		if l.store.Count(key, quotaWindow) >= limits.DailySendQuota {
+			return &RateLimitError{
+				Account:    config.Username,
+				Limit:      fmt.Sprintf("daily quota of %d messages reached", limits.DailySendQuota),
+				RetryAfter: l.store.ResetIn(key, quotaWindow),
+			}
+		}
+	}
+
+	if limits.DomainDailyQuota > 0 {
+		for _, domain := range recipientDomains(recipients) {
+			key := domainKey(config, domain)
+			if l.store.Count(key, quotaWindow) >= limits.DomainDailyQuota {
+				return &RateLimitError{
+					Account:    config.Username,
+					Limit:      fmt.Sprintf("daily quota of %d messages to %s reached", limits.DomainDailyQuota, domain),
+					RetryAfter: l.store.ResetIn(key, quotaWindow),
+				}
+			}
+		}
+	}
+
+	if limits.SendPerMinute > 0 {
+		wait, err := l.store.Take(sendRateKey(config), limits.SendPerMinute, burst(limits.SendPerMinute, limits.SendBurst))
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return &RateLimitError{
+				Account:    config.Username,
+				Limit:      fmt.Sprintf("send rate of %g messages per minute", limits.SendPerMinute),
+				RetryAfter: wait,
+			}
+		}
+	}
+
+	if err := l.store.Record(quotaWindow, quotaKeys(config, recipients)...); err != nil {
+		log.Printf("Warning: failed to record send against quota: %v", err)
+	}
+	return nil
+}
+
+// releaseSend gives back the daily quota slot reserved by checkSend for a
+// message that was not delivered
+func (l *Limiter) releaseSend(config *types.EmailConfig, recipients []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.store.Release(quotaKeys(config, recipients)...)
+}
+
+// quotaKeys returns the daily quota keys a message to recipients counts against
+func quotaKeys(config *types.EmailConfig, recipients []string) []string {
+	keys := []string{sendKey(config)}
+	for _, domain := range recipientDomains(recipients) {
+		keys = append(keys, domainKey(config, domain))
+	}
+	return keys
+}
+
+// checkRead consumes one IMAP request token for the account
+func (l *Limiter) checkRead(config *types.EmailConfig) error {
+	limits := config.Limits
+	if limits.ReadPerMinute <= 0 {
+		return nil
+	}
+
+	wait, err := l.store.Take(readRateKey(config), limits.ReadPerMinute, burst(limits.ReadPerMinute, limits.ReadBurst))
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &RateLimitError{
+			Account:    config.Username,
+			Limit:      fmt.Sprintf("read rate of %g requests per minute", limits.ReadPerMinute),
+			RetryAfter: wait,
+		}
+	}
+	return nil
+}
+
+// status reports the remaining capacity of an account without consuming any
+func (l *Limiter) status(config *types.EmailConfig) QuotaStatus {
+	limits := config.Limits
+	key := sendKey(config)
+
+	status := QuotaStatus{
+		Account:            config.Username,
+		DailySent:          l.store.Count(key, quotaWindow),
+		DailyQuota:         limits.DailySendQuota,
+		DomainDailyQuota:   limits.DomainDailyQuota,
+		SendMessagesPerMin: limits.SendPerMinute,
+		ReadRequestsPerMin: limits.ReadPerMinute,
+	}
+
+	if limits.SendPerMinute > 0 {
+		tokens := l.store.Tokens(sendRateKey(config), limits.SendPerMinute, burst(limits.SendPerMinute, limits.SendBurst))
+		status.SendTokens = &tokens
+	}
+	if limits.ReadPerMinute > 0 {
+		tokens := l.store.Tokens(readRateKey(config), limits.ReadPerMinute, burst(limits.ReadPerMinute, limits.ReadBurst))
+		status.ReadTokens = &tokens
+	}
+	if limits.DailySendQuota > 0 {
+		remaining := max(limits.DailySendQuota-status.DailySent, 0)
+		status.DailyRemaining = &remaining
+		if status.DailySent > 0 {
+			status.QuotaResetsIn = l.store.ResetIn(key, quotaWindow).Round(time.Minute).String()
+		}
+	}
+
+	return status
+}
+
+// burst returns the bucket capacity, defaulting to one minute's worth of tokens
+func burst(perMinute float64, configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	return max(1, int(math.Ceil(perMinute)))
+}
+
+func recipientDomains(recipients []string) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, r := range recipients {
+		d := domainOf(strings.ToLower(r))
+		if d != "" && !seen[d] {
+			seen[d] = true
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+func sendKey(config *types.EmailConfig) string     { return "send:" + config.Username }
+func sendRateKey(config *types.EmailConfig) string { return "send-rate:" + config.Username }
+func readRateKey(config *types.EmailConfig) string { return "read-rate:" + config.Username }
+
+func domainKey(config *types.EmailConfig, domain string) string {
+	return "send:" + config.Username + ":" + domain
+}
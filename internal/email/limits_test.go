@@ -0,0 +1,79 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"ai-presence-mcp/internal/ratelimit"
+	"ai-presence-mcp/pkg/types"
+)
+
+func limitedService(t *testing.T, quota int) *Service {
+	t.Helper()
+
+	store, err := ratelimit.Open("")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	service := NewService([]types.EmailConfig{{
+		Username:   "agent@example.com",
+		Password:   "secret",
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   1, // nothing listens here, so every send fails to connect
+		Limits:     types.AccountLimits{DailySendQuota: quota},
+	}})
+	service.SetLimiter(NewLimiter(store))
+	return service
+}
+
+func TestCheckSendReservesQuota(t *testing.T) {
+	service := limitedService(t, 1)
+	config := &service.accounts()[0]
+	recipients := []string{"friend@example.com"}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed := 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if service.limiter.checkSend(config, recipients) == nil {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if passed != 1 {
+		t.Fatalf("expected exactly one send to take the last quota slot, got %d", passed)
+	}
+
+	if err := service.limiter.releaseSend(config, recipients); err != nil {
+		t.Fatalf("releaseSend: %v", err)
+	}
+	if err := service.limiter.checkSend(config, recipients); err != nil {
+		t.Errorf("expected the released slot to be available, got %v", err)
+	}
+}
+
+func TestFailedSendReleasesQuota(t *testing.T) {
+	service := limitedService(t, 1)
+
+	for i := 0; i < 2; i++ {
+		err := service.SendEmail(context.Background(), "friend@example.com", "Hi", "Body", "", "")
+		if err == nil {
+			t.Fatal("expected the send to fail")
+		}
+		var rateErr *RateLimitError
+		if errors.As(err, &rateErr) {
+			t.Fatalf("expected a failed send not to use up the quota, got %v", err)
+		}
+	}
+	if got := service.Quotas()[0].DailySent; got != 0 {
+		t.Errorf("expected no sends counted, got %d", got)
+	}
+}
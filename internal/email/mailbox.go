@@ -0,0 +1,194 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"ai-presence-mcp/internal/progress"
+	"ai-presence-mcp/pkg/types"
+
+	"github.com/emersion/go-imap"
+)
+
+// FolderState is the IMAP STATUS of a folder, compared between checks to
+// detect new mail
+type FolderState struct {
+	Messages    uint32
+	UIDNext     uint32
+	UIDValidity uint32
+	Unseen      uint32
+}
+
+// FolderStatus returns the current state of folder without selecting it
+func (s *Service) FolderStatus(ctx context.Context, account, folder string) (FolderState, error) {
+	config, err := s.getConfig(account)
+	if err != nil {
+		return FolderState{}, err
+	}
+
+	if err := s.checkRead(config); err != nil {
+		return FolderState{}, err
+	}
+
+	c, err := s.connectIMAP(ctx, config)
+	if err != nil {
+		return FolderState{}, err
+	}
+	defer c.Logout()
+
+	if folder == "" {
+		folder = "INBOX"
+	}
+	status, err := c.Status(folder, []imap.StatusItem{
+		imap.StatusMessages, imap.StatusUidNext, imap.StatusUidValidity, imap.StatusUnseen,
+	})
+	if err != nil {
+		return FolderState{}, fmt.Errorf("failed to get status of folder %s: %w", folder, interrupted(ctx, err))
+	}
+
+	return FolderState{
+		Messages:    status.Messages,
+		UIDNext:     status.UidNext,
+		UIDValidity: status.UidValidity,
+		Unseen:      status.Unseen,
+	}, nil
+}
+
+// ListFolders returns the names of the account's folders
+func (s *Service) ListFolders(ctx context.Context, account string) ([]string, error) {
+	config, err := s.getConfig(account)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRead(config); err != nil {
+		return nil, err
+	}
+
+	c, err := s.connectIMAP(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout()
+
+	mailboxes := make(chan *imap.MailboxInfo, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.List("", "*", mailboxes)
+	}()
+
+	var folders []string
+	for m := range mailboxes {
+		if !hasFlag(m.Attributes, imap.NoSelectAttr) {
+			folders = append(folders, m.Name)
+		}
+	}
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", interrupted(ctx, err))
+	}
+	sort.Strings(folders)
+	return folders, nil
+}
+
+// PeekedEmail is a message fetched with its RFC 5322 source
+type PeekedEmail struct {
+	types.EmailMessage
+	Source []byte
+}
+
+// PeekEmail fetches a message and its RFC 5322 source by UID without
+// marking it as read
+func (s *Service) PeekEmail(ctx context.Context, uid uint32, folder, account string) (*types.EmailMessage, []byte, error) {
+	emails, err := s.PeekEmails(ctx, []uint32{uid}, folder, account)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(emails) == 0 {
+		return nil, nil, fmt.Errorf("email with UID %d not found", uid)
+	}
+	return &emails[0].EmailMessage, emails[0].Source, nil
+}
+
+// PeekEmails fetches several messages by UID over one connection without
+// marking them as read. Messages that no longer exist are left out; the
+// rest are returned in UID order.
+func (s *Service) PeekEmails(ctx context.Context, uids []uint32, folder, account string) ([]PeekedEmail, error) {
+	config, err := s.getConfig(account)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRead(config); err != nil {
+		return nil, err
+	}
+
+	c, err := s.connectIMAP(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout()
+
+	if folder == "" {
+		folder = "INBOX"
+	}
+	if _, err := c.Select(folder, true); err != nil {
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, interrupted(ctx, err))
+	}
+
+	seqSet := &imap.SeqSet{}
+	seqSet.AddNum(uids...)
+
+	section := &imap.BodySectionName{Peek: true}
+	items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchUid, section.FetchItem()}
+	messages := make(chan *imap.Message, len(uids))
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.UidFetch(seqSet, items, messages)
+	}()
+
+	var emails []PeekedEmail
+	var fetched int
+	for msg := range messages {
+		fetched++
+		progress.Report(ctx, float64(fetched), float64(len(uids)), fmt.Sprintf("Fetched %d of %d messages from %s", fetched, len(uids), folder))
+
+		if msg.Envelope == nil {
+			continue
+		}
+
+		email := PeekedEmail{EmailMessage: types.EmailMessage{
+			ID:      msg.Uid,
+			Subject: msg.Envelope.Subject,
+			Date:    msg.Envelope.Date.Format(time.RFC3339),
+			Unread:  !hasFlag(msg.Flags, imap.SeenFlag),
+			Folder:  folder,
+		}}
+		if len(msg.Envelope.From) > 0 {
+			email.From = msg.Envelope.From[0].Address()
+		}
+		for _, addr := range msg.Envelope.To {
+			email.To = append(email.To, addr.Address())
+		}
+
+		r := msg.GetBody(section)
+		if r == nil {
+			continue
+		}
+		if email.Source, err = io.ReadAll(r); err != nil {
+			continue
+		}
+		email.Body = messageBody(email.Source)
+		emails = append(emails, email)
+	}
+
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("failed to fetch messages: %w", interrupted(ctx, err))
+	}
+
+	sort.Slice(emails, func(i, j int) bool { return emails[i].ID < emails[j].ID })
+	return emails, nil
+}
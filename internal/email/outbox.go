@@ -0,0 +1,448 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+	"ai-presence-mcp/pkg/utils"
+
+	gomail "github.com/wneessen/go-mail"
+)
+
+// Outbox entry states
+const (
+	OutboxPending   = "pending"
+	OutboxSent      = "sent"
+	OutboxFailed    = "failed"
+	OutboxCancelled = "cancelled"
+)
+
+// OutboxEntry is a single queued message together with its delivery state
+type OutboxEntry struct {
+	ID          string     `json:"id"`
+	Account     string     `json:"account,omitempty"`
+	Identity    string     `json:"identity,omitempty"`
+	To          string     `json:"to"`
+	Subject     string     `json:"subject"`
+	Body        string     `json:"body"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	SendAt      time.Time  `json:"send_at"`
+	NextAttempt time.Time  `json:"next_attempt"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+}
+
+// deliveryLease is how long an entry claimed for delivery is left alone by
+// other workers sharing the outbox file. An entry whose worker crashed
+// mid-delivery is retried once it runs out.
+const deliveryLease = 5 * time.Minute
+
+// Outbox is a persistent, file-backed queue of outgoing messages. Entries are
+// delivered by Run once their send time is reached and retried with
+// exponential backoff when delivery fails with a transient error. Every
+// mutation re-reads the file under a lock so that servers sharing it never
+// overwrite each other's entries or deliver the same one twice.
+type Outbox struct {
+	service *Service
+	config  types.OutboxConfig
+
+	mu      sync.Mutex
+	entries []*OutboxEntry
+	wake    chan struct{}
+
+	// paused is set by the worker while dry-run mode holds delivery back
+	paused bool
+}
+
+// NewOutbox opens the outbox stored at config.Path, creating it on first use
+func NewOutbox(service *Service, config types.OutboxConfig) (*Outbox, error) {
+	o := &Outbox{
+		service: service,
+		config:  config,
+		wake:    make(chan struct{}, 1),
+	}
+
+	entries, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	o.entries = entries
+
+	return o, nil
+}
+
+// Enqueue adds a message to the outbox. A zero sendAt means "as soon as possible".
+func (o *Outbox) Enqueue(to, subject, body, account, identity string, sendAt time.Time) (*OutboxEntry, error) {
+	// Refuse messages that could never be delivered before queueing them
+	if err := o.service.CheckEmail(to, subject, body, account, identity); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate outbox id: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if sendAt.IsZero() || sendAt.Before(now) {
+		sendAt = now
+	}
+
+	entry := &OutboxEntry{
+		ID:          id,
+		Account:     account,
+		Identity:    identity,
+		To:          to,
+		Subject:     subject,
+		Body:        body,
+		Status:      OutboxPending,
+		CreatedAt:   now,
+		SendAt:      sendAt.UTC(),
+		NextAttempt: sendAt.UTC(),
+	}
+
+	err = o.update(func(entries []*OutboxEntry) ([]*OutboxEntry, error) {
+		return append(entries, entry), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.notify()
+	copied := *entry
+	return &copied, nil
+}
+
+// List returns copies of the outbox entries ordered by send time. An empty
+// status returns every entry.
+func (o *Outbox) List(status string) []OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Pick up entries written by other servers; the file is replaced
+	// atomically, so it can be read without the lock
+	if entries, err := o.load(); err != nil {
+		log.Printf("Outbox: %v", err)
+	} else {
+		o.entries = entries
+	}
+
+	result := []OutboxEntry{}
+	for _, e := range o.entries {
+		if status == "" || e.Status == status {
+			result = append(result, *e)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SendAt.Before(result[j].SendAt)
+	})
+	return result
+}
+
+// Cancel stops a pending entry from being delivered
+func (o *Outbox) Cancel(id string) (*OutboxEntry, error) {
+	var cancelled OutboxEntry
+	err := o.update(func(entries []*OutboxEntry) ([]*OutboxEntry, error) {
+		e := findEntry(entries, id)
+		if e == nil {
+			return nil, fmt.Errorf("outbox entry not found: %s", id)
+		}
+		if e.Status != OutboxPending {
+			return nil, fmt.Errorf("outbox entry %s is %s and cannot be cancelled", id, e.Status)
+		}
+		e.Status = OutboxCancelled
+		cancelled = *e
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cancelled, nil
+}
+
+// Run delivers due entries until ctx is cancelled
+func (o *Outbox) Run(ctx context.Context) {
+	log.Printf("Outbox worker started (%s)", o.config.Path)
+
+	for {
+		o.deliverDue(ctx)
+
+		timer := time.NewTimer(o.nextWait())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("Outbox worker stopped")
+			return
+		case <-o.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// nextWait returns how long the worker may sleep before an entry becomes due
+func (o *Outbox) nextWait() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	wait := o.config.PollInterval
+	if o.service.DryRun() {
+		return wait
+	}
+	now := time.Now()
+	for _, e := range o.entries {
+		if e.Status != OutboxPending {
+			continue
+		}
+		if d := e.NextAttempt.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// deliverDue sends the due entries. Nothing is sent while server-wide
+// dry-run mode is on: entries stay pending until it is turned off.
+func (o *Outbox) deliverDue(ctx context.Context) {
+	if dryRun := o.service.DryRun(); dryRun != o.paused {
+		o.paused = dryRun
+		if dryRun {
+			log.Printf("Outbox: delivery paused while dry-run mode is on")
+		} else {
+			log.Printf("Outbox: delivery resumed")
+		}
+	}
+	if o.paused {
+		return
+	}
+
+	for _, entry := range o.due() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := o.service.SendEmail(ctx, entry.To, entry.Subject, entry.Body, entry.Account, entry.Identity)
+		o.finish(ctx, entry.ID, err)
+	}
+}
+
+// finish records the outcome of delivering entry id. A delivery cut short by
+// shutdown is not counted as an attempt; the claim is released instead so
+// the entry is retried as soon as the outbox runs again.
+func (o *Outbox) finish(ctx context.Context, id string, sendErr error) {
+	if sendErr == nil || ctx.Err() == nil {
+		o.recordAttempt(id, sendErr)
+		return
+	}
+
+	err := o.update(func(entries []*OutboxEntry) ([]*OutboxEntry, error) {
+		if entry := findEntry(entries, id); entry != nil && entry.Status == OutboxPending {
+			entry.NextAttempt = time.Now().UTC()
+		}
+		return entries, nil
+	})
+	if err != nil {
+		log.Printf("Outbox: %v", err)
+	}
+}
+
+// due claims the pending entries whose next attempt has been reached, moving
+// their next attempt past the delivery lease, and returns copies of them
+func (o *Outbox) due() []OutboxEntry {
+	var result []OutboxEntry
+	err := o.update(func(entries []*OutboxEntry) ([]*OutboxEntry, error) {
+		now := time.Now().UTC()
+		for _, e := range entries {
+			if e.Status == OutboxPending && !e.NextAttempt.After(now) {
+				e.NextAttempt = now.Add(deliveryLease)
+				result = append(result, *e)
+			}
+		}
+		return entries, nil
+	})
+	if err != nil {
+		log.Printf("Outbox: %v", err)
+		return nil
+	}
+	return result
+}
+
+func (o *Outbox) recordAttempt(id string, sendErr error) {
+	err := o.update(func(entries []*OutboxEntry) ([]*OutboxEntry, error) {
+		o.applyAttempt(findEntry(entries, id), sendErr)
+		return entries, nil
+	})
+	if err != nil {
+		log.Printf("Outbox: %v", err)
+	}
+}
+
+// applyAttempt records the outcome of a delivery on entry
+func (o *Outbox) applyAttempt(entry *OutboxEntry, sendErr error) {
+	// The entry may have been cancelled while the delivery was in flight
+	if entry == nil || entry.Status != OutboxPending {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	// Dry-run mode was turned on while the delivery was in flight; the
+	// entry waits for it to be turned off
+	if errors.Is(sendErr, ErrDryRun) {
+		entry.NextAttempt = now
+		return
+	}
+
+	// Hitting a rate limit is not a delivery failure; wait for capacity
+	var rateErr *RateLimitError
+	if errors.As(sendErr, &rateErr) {
+		entry.LastError = sendErr.Error()
+		entry.NextAttempt = now.Add(rateErr.RetryAfter)
+		log.Printf("Outbox: %s deferred until %s: %v", entry.ID, entry.NextAttempt.Format(time.RFC3339), sendErr)
+		return
+	}
+
+	entry.Attempts++
+
+	switch {
+	case sendErr == nil:
+		entry.Status = OutboxSent
+		entry.SentAt = &now
+		entry.LastError = ""
+		log.Printf("Outbox: delivered %s to %s", entry.ID, entry.To)
+	case !isTransientSendError(sendErr) || entry.Attempts >= o.config.MaxAttempts:
+		entry.Status = OutboxFailed
+		entry.LastError = sendErr.Error()
+		log.Printf("Outbox: giving up on %s after %d attempt(s): %v", entry.ID, entry.Attempts, sendErr)
+	default:
+		entry.LastError = sendErr.Error()
+		entry.NextAttempt = now.Add(o.backoff(entry.Attempts))
+		log.Printf("Outbox: attempt %d for %s failed, retrying at %s: %v",
+			entry.Attempts, entry.ID, entry.NextAttempt.Format(time.RFC3339), sendErr)
+	}
+}
+
+// backoff returns the delay before the next attempt, doubling from
+// RetryBaseDelay and capped at RetryMaxDelay
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.config.RetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= o.config.RetryMaxDelay {
+			return o.config.RetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// update loads the outbox file under a lock, applies fn and writes the
+// result back, keeping it as the in-memory copy
+func (o *Outbox) update(fn func([]*OutboxEntry) ([]*OutboxEntry, error)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	unlock, err := utils.LockFile(o.config.Path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := o.load()
+	if err != nil {
+		return err
+	}
+
+	entries, err = fn(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := o.save(entries); err != nil {
+		return err
+	}
+	o.entries = entries
+	return nil
+}
+
+func (o *Outbox) load() ([]*OutboxEntry, error) {
+	data, err := os.ReadFile(o.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	var entries []*OutboxEntry
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse outbox %s: %w", o.config.Path, err)
+		}
+	}
+	return entries, nil
+}
+
+// save writes the outbox to disk atomically
+func (o *Outbox) save(entries []*OutboxEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(o.config.Path, data); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return nil
+}
+
+func findEntry(entries []*OutboxEntry, id string) *OutboxEntry {
+	for _, e := range entries {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// isTransientSendError reports whether a delivery failure is worth retrying.
+// Permanent SMTP rejections (5xx) are not.
+func isTransientSendError(err error) bool {
+	var policyErr *PolicyViolationError
+	if errors.As(err, &policyErr) {
+		return false
+	}
+	var accountErr *AccountError
+	if errors.As(err, &accountErr) {
+		return false
+	}
+
+	var sendErr *gomail.SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.IsTemp() || sendErr.Reason == gomail.ErrConnCheck
+	}
+
+	// Anything else happened before the message reached the server
+	// (DNS, dial, TLS, authentication) and may succeed later
+	return true
+}
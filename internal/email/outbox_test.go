@@ -0,0 +1,231 @@
+package email
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func newTestOutbox(t *testing.T, path string) *Outbox {
+	t.Helper()
+
+	service := NewService([]types.EmailConfig{{
+		Username:   "agent@example.com",
+		Password:   "secret",
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   1, // nothing listens here, so every attempt fails to connect
+	}})
+
+	outbox, err := NewOutbox(service, types.OutboxConfig{
+		Path:           path,
+		MaxAttempts:    2,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  time.Hour,
+		PollInterval:   time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewOutbox: %v", err)
+	}
+	return outbox
+}
+
+func TestOutboxPersistsAndCancels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	outbox := newTestOutbox(t, path)
+
+	sendAt := time.Now().Add(time.Hour)
+	entry, err := outbox.Enqueue("friend@example.com", "Later", "Hello", "", "", sendAt)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	reopened := newTestOutbox(t, path)
+	pending := reopened.List(OutboxPending)
+	if len(pending) != 1 || pending[0].ID != entry.ID {
+		t.Fatalf("expected entry %s to survive reopening, got %+v", entry.ID, pending)
+	}
+
+	if _, err := reopened.Cancel(entry.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if _, err := reopened.Cancel(entry.ID); err == nil {
+		t.Error("expected cancelling a cancelled entry to fail")
+	}
+	if got := newTestOutbox(t, path).List(OutboxCancelled); len(got) != 1 {
+		t.Errorf("expected 1 cancelled entry on disk, got %d", len(got))
+	}
+}
+
+func TestOutboxSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	first, second := newTestOutbox(t, path), newTestOutbox(t, path)
+
+	a, err := first.Enqueue("friend@example.com", "From first", "Hello", "", "", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := second.Enqueue("friend@example.com", "From second", "Hello", "", "", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got := first.List(OutboxPending); len(got) != 2 {
+		t.Fatalf("expected both entries to be kept, got %+v", got)
+	}
+
+	if _, err := second.Cancel(a.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if got := first.List(OutboxCancelled); len(got) != 1 || got[0].ID != a.ID {
+		t.Errorf("expected the other outbox to see the cancellation, got %+v", got)
+	}
+}
+
+func TestOutboxClaimsDueEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	first, second := newTestOutbox(t, path), newTestOutbox(t, path)
+
+	if _, err := first.Enqueue("friend@example.com", "Now", "Hello", "", "", time.Time{}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got := first.due(); len(got) != 1 {
+		t.Fatalf("expected the entry to be due, got %+v", got)
+	}
+	if got := second.due(); len(got) != 0 {
+		t.Errorf("expected a claimed entry not to be delivered twice, got %+v", got)
+	}
+}
+
+func TestOutboxRejectsUnknownAccount(t *testing.T) {
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.json"))
+
+	if _, err := outbox.Enqueue("friend@example.com", "Hi", "Hello", "nobody@example.com", "", time.Time{}); err == nil {
+		t.Error("expected an error for an unknown account")
+	}
+}
+
+func TestOutboxRetriesWithBackoff(t *testing.T) {
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.json"))
+
+	entry, err := outbox.Enqueue("friend@example.com", "Now", "Hello", "", "", time.Time{})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	outbox.deliverDue(context.Background())
+
+	pending := outbox.List(OutboxPending)
+	if len(pending) != 1 {
+		t.Fatalf("expected the entry to stay pending after a connection failure, got %+v", outbox.List(""))
+	}
+	if pending[0].Attempts != 1 || pending[0].LastError == "" {
+		t.Errorf("expected one recorded attempt with an error, got %+v", pending[0])
+	}
+	if wait := time.Until(pending[0].NextAttempt); wait < 50*time.Second {
+		t.Errorf("expected the retry to be scheduled about a minute out, got %s", wait)
+	}
+
+	// Force the retry to be due; the second failure exhausts MaxAttempts
+	outbox.update(func(entries []*OutboxEntry) ([]*OutboxEntry, error) {
+		entries[0].NextAttempt = time.Now()
+		return entries, nil
+	})
+	outbox.deliverDue(context.Background())
+
+	failed := outbox.List(OutboxFailed)
+	if len(failed) != 1 || failed[0].ID != entry.ID {
+		t.Fatalf("expected the entry to fail after max attempts, got %+v", outbox.List(""))
+	}
+}
+
+func TestOutboxFailsUndeliverableEntries(t *testing.T) {
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.json"))
+
+	entry, err := outbox.Enqueue("friend@example.com", "Now", "Hello", "", "", time.Time{})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// The account stops being allowed to send after the entry was queued
+	outbox.service.SetAccounts([]types.EmailConfig{{Username: "agent@example.com", NoSend: true}})
+	outbox.deliverDue(context.Background())
+
+	failed := outbox.List(OutboxFailed)
+	if len(failed) != 1 || failed[0].ID != entry.ID || failed[0].Attempts != 1 {
+		t.Fatalf("expected the entry to fail without retries, got %+v", outbox.List(""))
+	}
+}
+
+func TestOutboxShutdownDoesNotCountAttempt(t *testing.T) {
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.json"))
+
+	entry, err := outbox.Enqueue("friend@example.com", "Now", "Hello", "", "", time.Time{})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got := outbox.due(); len(got) != 1 {
+		t.Fatalf("expected the entry to be claimed, got %+v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	outbox.finish(ctx, entry.ID, context.Canceled)
+
+	pending := outbox.List(OutboxPending)
+	if len(pending) != 1 || pending[0].Attempts != 0 || pending[0].NextAttempt.After(time.Now()) {
+		t.Fatalf("expected the entry to be due again without a counted attempt, got %+v", pending)
+	}
+}
+
+func TestOutboxPausesInDryRun(t *testing.T) {
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.json"))
+
+	entry, err := outbox.Enqueue("friend@example.com", "Now", "Hello", "", "", time.Time{})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	outbox.service.SetDryRun(true)
+	outbox.deliverDue(context.Background())
+	pending := outbox.List(OutboxPending)
+	if len(pending) != 1 || pending[0].Attempts != 0 {
+		t.Fatalf("expected the entry to wait untouched in dry-run mode, got %+v", outbox.List(""))
+	}
+	if wait := outbox.nextWait(); wait != time.Second {
+		t.Errorf("expected the worker to poll while paused, got %s", wait)
+	}
+
+	// An attempt that raced with dry-run being turned on is not counted
+	outbox.recordAttempt(entry.ID, ErrDryRun)
+	if got := outbox.List(OutboxPending); len(got) != 1 || got[0].Attempts != 0 {
+		t.Errorf("expected ErrDryRun to leave the entry pending, got %+v", got)
+	}
+
+	outbox.service.SetDryRun(false)
+	outbox.deliverDue(context.Background())
+	if got := outbox.List(OutboxPending); len(got) != 1 || got[0].Attempts != 1 {
+		t.Errorf("expected delivery to resume once dry-run is off, got %+v", got)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	outbox := &Outbox{config: types.OutboxConfig{
+		RetryBaseDelay: 30 * time.Second,
+		RetryMaxDelay:  5 * time.Minute,
+	}}
+
+	expected := []time.Duration{
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		5 * time.Minute,
+		5 * time.Minute,
+	}
+	for i, want := range expected {
+		if got := outbox.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, want)
+		}
+	}
+}
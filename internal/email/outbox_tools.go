@@ -0,0 +1,152 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// ListOutboxTool implements the MCP Tool interface for inspecting the outbox
+type ListOutboxTool struct {
+	outbox *Outbox
+}
+
+func NewListOutboxTool(outbox *Outbox) *ListOutboxTool {
+	return &ListOutboxTool{outbox: outbox}
+}
+
+func (t *ListOutboxTool) Name() string {
+	return "list_outbox"
+}
+
+func (t *ListOutboxTool) Description() string {
+	return "List emails in the outbox, including scheduled emails that have not been sent yet and emails waiting to be retried after a delivery failure."
+}
+
+func (t *ListOutboxTool) InputSchema() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{
+				"type":        "string",
+				"description": "Only list entries with this status: pending, sent, failed or cancelled (optional, defaults to pending)",
+				"enum":        []string{OutboxPending, OutboxSent, OutboxFailed, OutboxCancelled, "all"},
+			},
+		},
+	}
+}
+
+func (t *ListOutboxTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	status, _ := args["status"].(string)
+	switch status {
+	case "":
+		status = OutboxPending
+	case "all":
+		status = ""
+	}
+
+	entries := t.outbox.List(status)
+	if len(entries) == 0 {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: "The outbox has no matching entries",
+			}},
+		}, nil
+	}
+
+	result := fmt.Sprintf("Found %d outbox entries:\n\n", len(entries))
+	for i, e := range entries {
+		result += fmt.Sprintf("%d. ID: %s\n   To: %s\n   Subject: %s\n   Status: %s\n   Send at: %s\n   Attempts: %d\n",
+			i+1, e.ID, e.To, e.Subject, e.Status, e.SendAt.Format(time.RFC3339), e.Attempts)
+		if e.Status == OutboxPending && e.Attempts > 0 {
+			result += fmt.Sprintf("   Next attempt: %s\n", e.NextAttempt.Format(time.RFC3339))
+		}
+		if e.LastError != "" {
+			result += fmt.Sprintf("   Last error: %s\n", e.LastError)
+		}
+		result += "\n"
+	}
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: result,
+		}},
+	}, nil
+}
+
+// CancelScheduledTool implements the MCP Tool interface for cancelling queued emails
+type CancelScheduledTool struct {
+	outbox *Outbox
+}
+
+func NewCancelScheduledTool(outbox *Outbox) *CancelScheduledTool {
+	return &CancelScheduledTool{outbox: outbox}
+}
+
+func (t *CancelScheduledTool) Name() string {
+	return "cancel_scheduled"
+}
+
+func (t *CancelScheduledTool) Description() string {
+	return "Cancel a scheduled or queued email in the outbox before it is sent. Use the ID returned by send_email or list_outbox."
+}
+
+func (t *CancelScheduledTool) InputSchema() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the outbox entry to cancel",
+			},
+		},
+		"required": []string{"id"},
+	}
+}
+
+// Preview describes the outbox entry that Execute would cancel, for human approval
+func (t *CancelScheduledTool) Preview(args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	for _, e := range t.outbox.List("") {
+		if e.ID == id {
+			return fmt.Sprintf("Cancel scheduled email %s\nTo: %s\nSubject: %s\nSend at: %s",
+				e.ID, e.To, e.Subject, e.SendAt.Format(time.RFC3339)), nil
+		}
+	}
+	return "", fmt.Errorf("outbox entry not found: %s", id)
+}
+
+func (t *CancelScheduledTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	id, ok := args["id"].(string)
+	if !ok || id == "" {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: "Error: 'id' parameter is required and must be a string",
+			}},
+			IsError: &[]bool{true}[0],
+		}, nil
+	}
+
+	entry, err := t.outbox.Cancel(id)
+	if err != nil {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Failed to cancel email: %v", err),
+			}},
+			IsError: &[]bool{true}[0],
+		}, nil
+	}
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: fmt.Sprintf("Cancelled email %s to %s (%s)", entry.ID, entry.To, entry.Subject),
+		}},
+	}, nil
+}
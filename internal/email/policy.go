@@ -0,0 +1,109 @@
+package email
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// PolicyViolationError is returned when a message is refused by an account's
+// outbound recipient policy
+type PolicyViolationError struct {
+	Account   string
+	Recipient string
+	Rule      string
+}
+
+func (e *PolicyViolationError) Error() string {
+	if e.Recipient == "" {
+		return fmt.Sprintf("policy violation for account %s: %s", e.Account, e.Rule)
+	}
+	return fmt.Sprintf("policy violation for account %s: recipient %s %s", e.Account, e.Recipient, e.Rule)
+}
+
+// checkPolicy enforces the account's recipient policy on the envelope
+// recipients of a message
+func checkPolicy(config *types.EmailConfig, recipients []string) error {
+	policy := config.Policy
+
+	if policy.MaxRecipients > 0 && len(recipients) > policy.MaxRecipients {
+		return &PolicyViolationError{
+			Account: config.Username,
+			Rule:    fmt.Sprintf("%d recipients exceeds the limit of %d", len(recipients), policy.MaxRecipients),
+		}
+	}
+
+	for _, rcpt := range recipients {
+		addr := strings.ToLower(strings.TrimSpace(rcpt))
+		domain := domainOf(addr)
+
+		violation := func(rule string) error {
+			return &PolicyViolationError{Account: config.Username, Recipient: addr, Rule: rule}
+		}
+
+		if matchAddress(policy.BlockedAddresses, addr) {
+			return violation("is blocked")
+		}
+		if matchDomain(policy.BlockedDomains, domain) {
+			return violation(fmt.Sprintf("is in blocked domain %s", domain))
+		}
+
+		if policy.InternalOnly {
+			internal := policy.InternalDomains
+			if len(internal) == 0 {
+				internal = []string{domainOf(strings.ToLower(config.Username))}
+			}
+			if !matchDomain(internal, domain) {
+				return violation("is outside the internal domains")
+			}
+		}
+
+		if len(policy.AllowedAddresses) > 0 || len(policy.AllowedDomains) > 0 {
+			if !matchAddress(policy.AllowedAddresses, addr) && !matchDomain(policy.AllowedDomains, domain) {
+				return violation("is not on the allowlist")
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchAddress reports whether addr matches any pattern. Patterns are full
+// addresses with optional shell-style wildcards, e.g. "*@example.com" or
+// "alerts-*@example.com".
+func matchAddress(patterns []string, addr string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(strings.TrimSpace(p)), addr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain reports whether domain matches any pattern. "example.com"
+// matches only itself; "*.example.com" matches example.com and every
+// subdomain.
+func matchDomain(patterns []string, domain string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if suffix, ok := strings.CutPrefix(p, "*."); ok {
+			if domain == suffix || strings.HasSuffix(domain, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, domain); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func domainOf(addr string) string {
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return addr[i+1:]
+	}
+	return ""
+}
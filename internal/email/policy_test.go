@@ -0,0 +1,107 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func TestCheckPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     types.RecipientPolicy
+		recipients []string
+		wantErr    bool
+	}{
+		{
+			name:       "no policy allows everything",
+			recipients: []string{"anyone@anywhere.org"},
+		},
+		{
+			name:       "blocked address wildcard",
+			policy:     types.RecipientPolicy{BlockedAddresses: []string{"*@competitor.com"}},
+			recipients: []string{"ceo@competitor.com"},
+			wantErr:    true,
+		},
+		{
+			name:       "blocked subdomain",
+			policy:     types.RecipientPolicy{BlockedDomains: []string{"*.example.net"}},
+			recipients: []string{"a@mail.example.net"},
+			wantErr:    true,
+		},
+		{
+			name:       "blocked wins over allowed",
+			policy:     types.RecipientPolicy{AllowedDomains: []string{"partner.com"}, BlockedAddresses: []string{"legal@partner.com"}},
+			recipients: []string{"LEGAL@partner.com"},
+			wantErr:    true,
+		},
+		{
+			name:       "allowlist by address",
+			policy:     types.RecipientPolicy{AllowedAddresses: []string{"boss@partner.com"}},
+			recipients: []string{"boss@partner.com"},
+		},
+		{
+			name:       "allowlist rejects others",
+			policy:     types.RecipientPolicy{AllowedDomains: []string{"partner.com"}},
+			recipients: []string{"boss@partner.com", "someone@else.com"},
+			wantErr:    true,
+		},
+		{
+			name:       "internal only defaults to account domain",
+			policy:     types.RecipientPolicy{InternalOnly: true},
+			recipients: []string{"colleague@example.com"},
+		},
+		{
+			name:       "internal only rejects external",
+			policy:     types.RecipientPolicy{InternalOnly: true},
+			recipients: []string{"friend@gmail.com"},
+			wantErr:    true,
+		},
+		{
+			name:       "internal domains with wildcard",
+			policy:     types.RecipientPolicy{InternalOnly: true, InternalDomains: []string{"*.corp.example"}},
+			recipients: []string{"ops@eu.corp.example"},
+		},
+		{
+			name:       "max recipients",
+			policy:     types.RecipientPolicy{MaxRecipients: 1},
+			recipients: []string{"a@example.com", "b@example.com"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.EmailConfig{Username: "agent@example.com", Policy: tt.policy}
+
+			err := checkPolicy(config, tt.recipients)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var policyErr *PolicyViolationError
+			if err != nil && !errors.As(err, &policyErr) {
+				t.Errorf("expected a PolicyViolationError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestSendEmailEnforcesPolicy(t *testing.T) {
+	service := NewService([]types.EmailConfig{{
+		Username:   "agent@example.com",
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   1,
+		Policy:     types.RecipientPolicy{InternalOnly: true},
+	}})
+
+	var policyErr *PolicyViolationError
+	if err := service.SendEmail(context.Background(), "friend@gmail.com", "Hi", "Hello", "", ""); !errors.As(err, &policyErr) {
+		t.Errorf("expected SendEmail to refuse an external recipient, got %v", err)
+	}
+	if _, err := service.PreviewEmail("friend@gmail.com", "Hi", "Hello", "", ""); !errors.As(err, &policyErr) {
+		t.Errorf("expected PreviewEmail to apply the policy, got %v", err)
+	}
+}
@@ -0,0 +1,480 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// Limits on how much mail a prompt embeds
+const (
+	triageDefaultLimit = 20
+	threadMaxMessages  = 20
+	threadScanLimit    = 100
+	digestScanLimit    = 200
+)
+
+// Argument descriptions shared by the prompts
+var (
+	accountArgument = types.PromptArgument{
+		Name:        "account",
+		Description: "Name or username of the account (optional, uses the default account if not specified)",
+	}
+	folderArgument = types.PromptArgument{
+		Name:        "folder",
+		Description: "Folder to use (optional, defaults to INBOX)",
+	}
+	uidArgument = types.PromptArgument{
+		Name:        "uid",
+		Description: "UID of the email, as shown by read_emails or a folder resource",
+		Required:    true,
+	}
+)
+
+// replyTones are the tones draft_reply offers
+var replyTones = []string{"brief", "formal", "friendly"}
+
+// mailPrompt holds what every email prompt needs
+type mailPrompt struct {
+	service *Service
+}
+
+// label returns the display name of the account selected by account
+func (p mailPrompt) label(account string) (string, error) {
+	config, err := p.service.getConfig(account)
+	if err != nil {
+		return "", err
+	}
+	return AccountLabel(config), nil
+}
+
+func (p mailPrompt) Complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error) {
+	return p.service.complete(ctx, argument, value, given)
+}
+
+// complete suggests accounts, identities, folders and recent message UIDs for prompt
+// arguments and resource URI variables
+func (s *Service) complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error) {
+	switch argument {
+	case "account":
+		var labels []string
+		configs := s.accounts()
+		for i := range configs {
+			labels = append(labels, AccountLabel(&configs[i]))
+		}
+		return matchPrefix(labels, value), nil
+
+	case "folder":
+		folders, err := s.ListFolders(ctx, given["account"])
+		if err != nil {
+			return nil, err
+		}
+		return matchPrefix(folders, value), nil
+
+	case "uid":
+		emails, err := s.ReadEmails(ctx, given["account"], given["folder"], triageDefaultLimit, false)
+		if err != nil {
+			return nil, err
+		}
+		var uids []string
+		for i := len(emails) - 1; i >= 0; i-- {
+			uids = append(uids, strconv.FormatUint(uint64(emails[i].ID), 10))
+		}
+		return matchPrefix(uids, value), nil
+
+	case "identity":
+		config, err := s.getConfig(given["account"])
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, id := range config.Identities {
+			if id.Name != "" {
+				names = append(names, id.Name)
+			} else {
+				names = append(names, identityFrom(config, &id))
+			}
+		}
+		return matchPrefix(names, value), nil
+	}
+	return nil, nil
+}
+
+// matchPrefix returns the candidates starting with value, ignoring case
+func matchPrefix(candidates []string, value string) []string {
+	matches := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c), strings.ToLower(value)) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// parseUID parses the uid argument of a prompt
+func parseUID(value string) (uint32, error) {
+	uid, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+	if err != nil || uid == 0 {
+		return 0, fmt.Errorf("uid must be a positive number, got %q", value)
+	}
+	return uint32(uid), nil
+}
+
+// positiveArgument parses an optional positive integer argument
+func positiveArgument(args map[string]string, name string, def int) (int, error) {
+	value := strings.TrimSpace(args[name])
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number, got %q", name, value)
+	}
+	return n, nil
+}
+
+func folderOrInbox(folder string) string {
+	if folder == "" {
+		return "INBOX"
+	}
+	return folder
+}
+
+// listing renders messages one per line, newest first
+func listing(account, folder string, emails []types.EmailMessage) string {
+	var b strings.Builder
+	for i := len(emails) - 1; i >= 0; i-- {
+		e := emails[i]
+		status := "read"
+		if e.Unread {
+			status = "unread"
+		}
+		fmt.Fprintf(&b, "- UID %d (%s) %s\n  From: %s\n  Subject: %s\n  URI: %s\n",
+			e.ID, status, e.Date, e.From, e.Subject, MessageURI(account, folder, e.ID))
+	}
+	return b.String()
+}
+
+// messageContent embeds a message in a prompt as its text/plain resource
+func messageContent(account string, email *types.EmailMessage) *types.ResourceContent {
+	return &types.ResourceContent{
+		URI:      MessageURI(account, email.Folder, email.ID),
+		MIMEType: "text/plain",
+		Text: fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\nDate: %s\n\n%s\n",
+			email.From, strings.Join(email.To, ", "), email.Subject, email.Date, email.Body),
+	}
+}
+
+// TriageInboxPrompt asks for the recent messages of a folder to be sorted
+// by what they need
+type TriageInboxPrompt struct {
+	mailPrompt
+}
+
+func NewTriageInboxPrompt(service *Service) *TriageInboxPrompt {
+	return &TriageInboxPrompt{mailPrompt{service}}
+}
+
+func (p *TriageInboxPrompt) Name() string {
+	return "triage_inbox"
+}
+
+func (p *TriageInboxPrompt) Description() string {
+	return "Sort the most recent messages of a folder into what needs a reply, what needs action, what to read later and what can be archived"
+}
+
+func (p *TriageInboxPrompt) Arguments() []types.PromptArgument {
+	return []types.PromptArgument{
+		accountArgument,
+		folderArgument,
+		{Name: "limit", Description: fmt.Sprintf("Number of recent messages to triage (optional, defaults to %d)", triageDefaultLimit)},
+	}
+}
+
+func (p *TriageInboxPrompt) Get(ctx context.Context, args map[string]string) (*types.PromptResult, error) {
+	limit, err := positiveArgument(args, "limit", triageDefaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	account, err := p.label(args["account"])
+	if err != nil {
+		return nil, err
+	}
+	folder := folderOrInbox(args["folder"])
+
+	emails, err := p.service.ReadEmails(ctx, account, folder, limit, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", folder, err)
+	}
+
+	text := fmt.Sprintf("Triage the %d most recent messages in %s of the account %s, listed below newest first.\n\n"+
+		"Sort every message into one of: needs a reply, needs action, read later, can be archived. "+
+		"For each give its UID, the category and a one-line reason, most urgent first. "+
+		"Use get_email_content or the message URI when the sender and subject are not enough to decide. "+
+		"Do not send, move or delete anything.\n\n", len(emails), folder, account)
+	if len(emails) == 0 {
+		text += "The folder is empty."
+	} else {
+		text += listing(account, folder, emails)
+	}
+
+	return &types.PromptResult{
+		Description: fmt.Sprintf("Triage of %s (%s)", folder, account),
+		Messages:    []types.PromptMessage{{Role: "user", Text: text}},
+	}, nil
+}
+
+// DraftReplyPrompt asks for a reply to one message to be drafted for review
+type DraftReplyPrompt struct {
+	mailPrompt
+}
+
+func NewDraftReplyPrompt(service *Service) *DraftReplyPrompt {
+	return &DraftReplyPrompt{mailPrompt{service}}
+}
+
+func (p *DraftReplyPrompt) Name() string {
+	return "draft_reply"
+}
+
+func (p *DraftReplyPrompt) Description() string {
+	return "Draft a reply to an email for review before anything is sent"
+}
+
+func (p *DraftReplyPrompt) Arguments() []types.PromptArgument {
+	return []types.PromptArgument{
+		uidArgument,
+		accountArgument,
+		folderArgument,
+		{Name: "identity", Description: "Identity of the account to reply as, by name or from address (optional, uses the account's default identity)"},
+		{Name: "tone", Description: "Tone of the reply: " + strings.Join(replyTones, ", ") + " (optional)"},
+		{Name: "instructions", Description: "What the reply should say (optional)"},
+	}
+}
+
+func (p *DraftReplyPrompt) Complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error) {
+	if argument == "tone" {
+		return matchPrefix(replyTones, value), nil
+	}
+	return p.mailPrompt.Complete(ctx, argument, value, given)
+}
+
+func (p *DraftReplyPrompt) Get(ctx context.Context, args map[string]string) (*types.PromptResult, error) {
+	uid, err := parseUID(args["uid"])
+	if err != nil {
+		return nil, err
+	}
+	config, err := p.service.getConfig(args["account"])
+	if err != nil {
+		return nil, err
+	}
+	account := AccountLabel(config)
+	identity := strings.TrimSpace(args["identity"])
+	if identity != "" {
+		if _, err := resolveIdentity(config, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	email, _, err := p.service.PeekEmail(ctx, uid, folderOrInbox(args["folder"]), account)
+	if err != nil {
+		return nil, err
+	}
+
+	sender := "the account " + account
+	if identity != "" {
+		sender += fmt.Sprintf(" with the identity %q", identity)
+	}
+	text := "Draft a reply to the email below"
+	if tone := strings.TrimSpace(args["tone"]); tone != "" {
+		text += " in a " + tone + " tone"
+	}
+	text += ". "
+	if instructions := strings.TrimSpace(args["instructions"]); instructions != "" {
+		text += "The reply should: " + instructions + ". "
+	}
+	text += fmt.Sprintf("Show me the draft and do not send it. Once I approve it, send it with send_email "+
+		"from %s to %s with the subject %q.", sender, email.From, replySubject(email.Subject))
+
+	return &types.PromptResult{
+		Description: fmt.Sprintf("Reply to %q from %s", email.Subject, email.From),
+		Messages: []types.PromptMessage{
+			{Role: "user", Text: text},
+			{Role: "user", Resource: messageContent(account, email)},
+		},
+	}, nil
+}
+
+// replyPrefix matches the reply and forward markers clients put before a
+// subject, possibly repeated
+var replyPrefix = regexp.MustCompile(`(?i)^\s*((re|fwd?|aw|wg|sv)(\[\d+\])?\s*:\s*)+`)
+
+// replySubject returns the subject of a reply to a message with subject
+func replySubject(subject string) string {
+	return "Re: " + threadSubject(subject)
+}
+
+// threadSubject strips reply and forward markers, so the messages of a
+// thread share it
+func threadSubject(subject string) string {
+	return strings.TrimSpace(replyPrefix.ReplaceAllString(subject, ""))
+}
+
+// thread returns the subject of the message with uid and up to max messages
+// of its thread: the message and the most recent others in its folder that
+// share its subject
+func (s *Service) thread(ctx context.Context, uid uint32, folder, account string, max int) (string, []PeekedEmail, error) {
+	email, _, err := s.PeekEmail(ctx, uid, folder, account)
+	if err != nil {
+		return "", nil, err
+	}
+
+	subject := threadSubject(email.Subject)
+	recent, err := s.ReadEmails(ctx, account, folder, threadScanLimit, false)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %w", folder, err)
+	}
+	uids := []uint32{uid}
+	for i := len(recent) - 1; i >= 0 && len(uids) < max; i-- {
+		if recent[i].ID != uid && strings.EqualFold(threadSubject(recent[i].Subject), subject) {
+			uids = append(uids, recent[i].ID)
+		}
+	}
+
+	if len(uids) == 1 {
+		return subject, []PeekedEmail{{EmailMessage: *email}}, nil
+	}
+	thread, err := s.PeekEmails(ctx, uids, folder, account)
+	if err != nil {
+		return "", nil, err
+	}
+	return subject, thread, nil
+}
+
+// SummarizeThreadPrompt asks for a conversation to be summarized
+type SummarizeThreadPrompt struct {
+	mailPrompt
+}
+
+func NewSummarizeThreadPrompt(service *Service) *SummarizeThreadPrompt {
+	return &SummarizeThreadPrompt{mailPrompt{service}}
+}
+
+func (p *SummarizeThreadPrompt) Name() string {
+	return "summarize_thread"
+}
+
+func (p *SummarizeThreadPrompt) Description() string {
+	return "Summarize the conversation an email belongs to: the recent messages of its folder with the same subject"
+}
+
+func (p *SummarizeThreadPrompt) Arguments() []types.PromptArgument {
+	return []types.PromptArgument{uidArgument, accountArgument, folderArgument}
+}
+
+func (p *SummarizeThreadPrompt) Get(ctx context.Context, args map[string]string) (*types.PromptResult, error) {
+	uid, err := parseUID(args["uid"])
+	if err != nil {
+		return nil, err
+	}
+	account, err := p.label(args["account"])
+	if err != nil {
+		return nil, err
+	}
+
+	subject, thread, err := p.service.thread(ctx, uid, folderOrInbox(args["folder"]), account, threadMaxMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []types.PromptMessage{{
+		Role: "user",
+		Text: fmt.Sprintf("Summarize the email thread %q below, %d message(s) oldest first. "+
+			"Cover who said what, the decisions made, open questions and anything I still need to do or answer.",
+			subject, len(thread)),
+	}}
+	for i := range thread {
+		messages = append(messages, types.PromptMessage{Role: "user", Resource: messageContent(account, &thread[i].EmailMessage)})
+	}
+
+	return &types.PromptResult{
+		Description: fmt.Sprintf("Summary of the thread %q", subject),
+		Messages:    messages,
+	}, nil
+}
+
+// WeeklyDigestPrompt asks for a digest of the mail received recently
+type WeeklyDigestPrompt struct {
+	mailPrompt
+}
+
+func NewWeeklyDigestPrompt(service *Service) *WeeklyDigestPrompt {
+	return &WeeklyDigestPrompt{mailPrompt{service}}
+}
+
+func (p *WeeklyDigestPrompt) Name() string {
+	return "weekly_digest"
+}
+
+func (p *WeeklyDigestPrompt) Description() string {
+	return "Write a digest of the mail received over the past week or another number of days"
+}
+
+func (p *WeeklyDigestPrompt) Arguments() []types.PromptArgument {
+	return []types.PromptArgument{
+		accountArgument,
+		folderArgument,
+		{Name: "days", Description: "Number of days to cover (optional, defaults to 7)"},
+	}
+}
+
+func (p *WeeklyDigestPrompt) Complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error) {
+	if argument == "days" {
+		return matchPrefix([]string{"1", "7", "14", "30"}, value), nil
+	}
+	return p.mailPrompt.Complete(ctx, argument, value, given)
+}
+
+func (p *WeeklyDigestPrompt) Get(ctx context.Context, args map[string]string) (*types.PromptResult, error) {
+	days, err := positiveArgument(args, "days", 7)
+	if err != nil {
+		return nil, err
+	}
+	account, err := p.label(args["account"])
+	if err != nil {
+		return nil, err
+	}
+	folder := folderOrInbox(args["folder"])
+
+	recent, err := p.service.ReadEmails(ctx, account, folder, digestScanLimit, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", folder, err)
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	var emails []types.EmailMessage
+	for _, e := range recent {
+		if date, err := time.Parse(time.RFC3339, e.Date); err == nil && date.After(since) {
+			emails = append(emails, e)
+		}
+	}
+
+	text := fmt.Sprintf("Write a digest of the %d message(s) received in %s of the account %s over the past %d day(s), listed below newest first. "+
+		"Group them by topic, and call out requests still waiting for my reply, deadlines and events, "+
+		"and senders I could unsubscribe from. Use get_email_content or the message URI to read a message in full where needed.\n\n",
+		len(emails), folder, account, days)
+	if len(emails) == 0 {
+		text += "No messages were received in this period."
+	} else {
+		text += listing(account, folder, emails)
+	}
+
+	return &types.PromptResult{
+		Description: fmt.Sprintf("Digest of the past %d day(s) in %s (%s)", days, folder, account),
+		Messages:    []types.PromptMessage{{Role: "user", Text: text}},
+	}, nil
+}
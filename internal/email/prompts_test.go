@@ -0,0 +1,89 @@
+package email
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func TestThreadSubject(t *testing.T) {
+	tests := map[string]string{
+		"Quarterly report":               "Quarterly report",
+		"Re: Quarterly report":           "Quarterly report",
+		"RE: Fwd: re:Quarterly report":   "Quarterly report",
+		"AW: WG: Quarterly report":       "Quarterly report",
+		"Re[2]: Quarterly report":        "Quarterly report",
+		"Regarding the quarterly report": "Regarding the quarterly report",
+	}
+	for subject, want := range tests {
+		if got := threadSubject(subject); got != want {
+			t.Errorf("threadSubject(%q) = %q, want %q", subject, got, want)
+		}
+	}
+	if got := replySubject("Re: Re: Lunch"); got != "Re: Lunch" {
+		t.Errorf("replySubject = %q, want Re: Lunch", got)
+	}
+}
+
+func TestPromptCompletions(t *testing.T) {
+	service := accountsService()
+
+	got, err := NewDraftReplyPrompt(service).Complete(context.Background(), "account", "a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"archive", "alerts@example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("account completions = %v, want %v", got, want)
+	}
+
+	got, _ = NewDraftReplyPrompt(service).Complete(context.Background(), "tone", "F", nil)
+	if want := []string{"formal", "friendly"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tone completions = %v, want %v", got, want)
+	}
+
+	got, _ = NewWeeklyDigestPrompt(service).Complete(context.Background(), "days", "1", nil)
+	if want := []string{"1", "14"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("days completions = %v, want %v", got, want)
+	}
+}
+
+func TestDraftReplyIdentity(t *testing.T) {
+	service := NewService([]types.EmailConfig{{
+		Name:     "work",
+		Username: "agent@example.com",
+		Identities: []types.Identity{
+			{Name: "support", From: "support@example.com"},
+			{Name: "sales", From: "sales@example.com"},
+		},
+	}})
+	prompt := NewDraftReplyPrompt(service)
+
+	got, err := prompt.Complete(context.Background(), "identity", "su", map[string]string{"account": "work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"support"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("identity completions = %v, want %v", got, want)
+	}
+
+	_, err = prompt.Get(context.Background(), map[string]string{"uid": "1", "identity": "billing"})
+	if err == nil {
+		t.Error("expected an identity the account does not own to be rejected")
+	}
+}
+
+func TestPromptArguments(t *testing.T) {
+	service := accountsService()
+
+	if _, err := NewDraftReplyPrompt(service).Get(context.Background(), map[string]string{"uid": "abc"}); err == nil {
+		t.Error("expected a non-numeric uid to be rejected")
+	}
+	if _, err := NewTriageInboxPrompt(service).Get(context.Background(), map[string]string{"limit": "-1"}); err == nil {
+		t.Error("expected a negative limit to be rejected")
+	}
+	if _, err := NewWeeklyDigestPrompt(service).Get(context.Background(), map[string]string{"account": "missing"}); err == nil {
+		t.Error("expected an unknown account to be rejected")
+	}
+}
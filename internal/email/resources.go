@@ -0,0 +1,283 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// ResourceScheme is the URI scheme of mailbox and message resources
+const ResourceScheme = "email"
+
+// Resource URI templates. Each variable is one percent-encoded path segment,
+// so a folder such as "[Gmail]/Sent Mail" appears as %5BGmail%5D%2FSent%20Mail.
+const (
+	FolderURITemplate  = ResourceScheme + "://{account}/{folder}"
+	MessageURITemplate = ResourceScheme + "://{account}/{folder}/{uid}"
+)
+
+// folderListingLimit is the number of most recent messages a folder
+// resource lists
+const folderListingLimit = 50
+
+// FolderURI returns the resource URI of a folder listing
+func FolderURI(account, folder string) string {
+	return ResourceScheme + "://" + escapeSegment(account) + "/" + escapeSegment(folder)
+}
+
+// MessageURI returns the resource URI of a message
+func MessageURI(account, folder string, uid uint32) string {
+	return FolderURI(account, folder) + "/" + strconv.FormatUint(uint64(uid), 10)
+}
+
+// ParseResourceURI splits a folder or message URI into its parts. uid is 0
+// for a folder.
+func ParseResourceURI(uri string) (account, folder string, uid uint32, err error) {
+	rest, ok := strings.CutPrefix(uri, ResourceScheme+"://")
+	if !ok {
+		return "", "", 0, fmt.Errorf("not an %s resource URI: %s", ResourceScheme, uri)
+	}
+
+	segments := strings.Split(rest, "/")
+	if len(segments) != 2 && len(segments) != 3 {
+		return "", "", 0, fmt.Errorf("resource URI must be %s or %s: %s", FolderURITemplate, MessageURITemplate, uri)
+	}
+	if account, err = url.PathUnescape(segments[0]); err != nil || account == "" {
+		return "", "", 0, fmt.Errorf("invalid account in resource URI %s", uri)
+	}
+	if folder, err = url.PathUnescape(segments[1]); err != nil || folder == "" {
+		return "", "", 0, fmt.Errorf("invalid folder in resource URI %s", uri)
+	}
+	if len(segments) == 3 {
+		n, err := strconv.ParseUint(segments[2], 10, 32)
+		if err != nil || n == 0 {
+			return "", "", 0, fmt.Errorf("invalid message UID in resource URI %s", uri)
+		}
+		uid = uint32(n)
+	}
+	return account, folder, uid, nil
+}
+
+// escapeSegment percent-encodes everything but the unreserved characters,
+// which is what a simple URI template variable matches
+func escapeSegment(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// AccountResources lists the inbox of every configured account
+func (s *Service) AccountResources() []types.Resource {
+	configs := s.accounts()
+	resources := make([]types.Resource, 0, len(configs))
+	for i := range configs {
+		label := AccountLabel(&configs[i])
+		resources = append(resources, types.Resource{
+			URI:         FolderURI(label, "INBOX"),
+			Name:        label + " inbox",
+			Description: fmt.Sprintf("The %d most recent messages in the inbox of %s", folderListingLimit, label),
+			MIMEType:    "application/json",
+		})
+	}
+	return resources
+}
+
+// FolderResource serves folder listings at FolderURITemplate
+type FolderResource struct {
+	service *Service
+}
+
+func NewFolderResource(service *Service) *FolderResource {
+	return &FolderResource{service: service}
+}
+
+func (r *FolderResource) Name() string {
+	return "email_folder"
+}
+
+func (r *FolderResource) Description() string {
+	return fmt.Sprintf("The %d most recent messages in a folder of an email account, each with the URI of its message resource. Subscribe to be notified when new mail arrives.", folderListingLimit)
+}
+
+func (r *FolderResource) URITemplate() string {
+	return FolderURITemplate
+}
+
+func (r *FolderResource) MIMEType() string {
+	return "application/json"
+}
+
+func (r *FolderResource) Subscribable(uri string) bool {
+	return true
+}
+
+func (r *FolderResource) Complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error) {
+	return r.service.complete(ctx, argument, value, given)
+}
+
+// folderEntry is one message in a folder listing
+type folderEntry struct {
+	URI     string   `json:"uri"`
+	ID      uint32   `json:"id"`
+	From    string   `json:"from"`
+	To      []string `json:"to,omitempty"`
+	Subject string   `json:"subject"`
+	Date    string   `json:"date"`
+	Unread  bool     `json:"unread"`
+}
+
+func (r *FolderResource) Read(ctx context.Context, uri string) ([]types.ResourceContent, error) {
+	account, folder, uid, err := ParseResourceURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if uid != 0 {
+		return nil, fmt.Errorf("%s is a message, not a folder", uri)
+	}
+
+	emails, err := r.service.ReadEmails(ctx, account, folder, folderListingLimit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first, as a mail client shows them
+	entries := make([]folderEntry, 0, len(emails))
+	for i := len(emails) - 1; i >= 0; i-- {
+		e := emails[i]
+		entries = append(entries, folderEntry{
+			URI:     MessageURI(account, folder, e.ID),
+			ID:      e.ID,
+			From:    e.From,
+			To:      e.To,
+			Subject: e.Subject,
+			Date:    e.Date,
+			Unread:  e.Unread,
+		})
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"account":  account,
+		"folder":   folder,
+		"messages": entries,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []types.ResourceContent{{URI: uri, MIMEType: "application/json", Text: string(data)}}, nil
+}
+
+// MessageResource serves messages at MessageURITemplate, both as readable
+// text and as their original RFC 5322 source
+type MessageResource struct {
+	service *Service
+}
+
+func NewMessageResource(service *Service) *MessageResource {
+	return &MessageResource{service: service}
+}
+
+func (r *MessageResource) Name() string {
+	return "email_message"
+}
+
+func (r *MessageResource) Description() string {
+	return "An email message, as text/plain with its main headers and body and as message/rfc822 with its original source. Reading it does not mark it as read."
+}
+
+func (r *MessageResource) URITemplate() string {
+	return MessageURITemplate
+}
+
+func (r *MessageResource) MIMEType() string {
+	return "text/plain"
+}
+
+// Subscribable is false since a message's content never changes
+func (r *MessageResource) Subscribable(uri string) bool {
+	return false
+}
+
+func (r *MessageResource) Complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error) {
+	return r.service.complete(ctx, argument, value, given)
+}
+
+func (r *MessageResource) Read(ctx context.Context, uri string) ([]types.ResourceContent, error) {
+	account, folder, uid, err := ParseResourceURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if uid == 0 {
+		return nil, fmt.Errorf("%s is a folder, not a message", uri)
+	}
+
+	email, source, err := r.service.PeekEmail(ctx, uid, folder, account)
+	if err != nil {
+		return nil, err
+	}
+
+	text := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\nDate: %s\n\n%s\n",
+		email.From, strings.Join(email.To, ", "), email.Subject, email.Date, email.Body)
+	return []types.ResourceContent{
+		{URI: uri, MIMEType: "text/plain", Text: text},
+		{URI: uri, MIMEType: "message/rfc822", Blob: source},
+	}, nil
+}
+
+// MailWatcher detects new mail in subscribed folders by comparing their
+// IMAP STATUS between checks
+type MailWatcher struct {
+	status func(ctx context.Context, account, folder string) (FolderState, error)
+	notify func(uri string)
+
+	mu     sync.Mutex
+	states map[string]FolderState
+}
+
+// NewMailWatcher returns a watcher calling notify with the URI of each
+// folder that changed
+func NewMailWatcher(service *Service, notify func(uri string)) *MailWatcher {
+	return &MailWatcher{
+		status: service.FolderStatus,
+		notify: notify,
+		states: make(map[string]FolderState),
+	}
+}
+
+// Check polls the folders named by the folder URIs in uris and notifies
+// those whose state changed since the previous check. A folder's first
+// check only records its state; message URIs are ignored.
+func (w *MailWatcher) Check(ctx context.Context, uris []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched := make(map[string]bool, len(uris))
+	for _, uri := range uris {
+		account, folder, uid, err := ParseResourceURI(uri)
+		if err != nil || uid != 0 {
+			continue
+		}
+		watched[uri] = true
+
+		state, err := w.status(ctx, account, folder)
+		if err != nil {
+			log.Printf("Warning: failed to check %s for new mail: %v", uri, err)
+			continue
+		}
+		previous, known := w.states[uri]
+		w.states[uri] = state
+		if known && state != previous {
+			w.notify(uri)
+		}
+	}
+
+	for uri := range w.states {
+		if !watched[uri] {
+			delete(w.states, uri)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package email
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/yosida95/uritemplate/v3"
+)
+
+func TestResourceURIs(t *testing.T) {
+	folderTemplate := uritemplate.MustNew(FolderURITemplate)
+	messageTemplate := uritemplate.MustNew(MessageURITemplate)
+
+	tests := []struct {
+		account, folder string
+		uid             uint32
+	}{
+		{"work", "INBOX", 0},
+		{"agent@example.com", "[Gmail]/Sent Mail", 0},
+		{"work", "Archive/2025", 42},
+	}
+	for _, tt := range tests {
+		uri, template := FolderURI(tt.account, tt.folder), folderTemplate
+		if tt.uid != 0 {
+			uri, template = MessageURI(tt.account, tt.folder, tt.uid), messageTemplate
+		}
+		if !template.Regexp().MatchString(uri) {
+			t.Errorf("%s does not match %s", uri, template.Raw())
+		}
+
+		account, folder, uid, err := ParseResourceURI(uri)
+		if err != nil {
+			t.Errorf("ParseResourceURI(%s): %v", uri, err)
+			continue
+		}
+		if account != tt.account || folder != tt.folder || uid != tt.uid {
+			t.Errorf("ParseResourceURI(%s) = %q, %q, %d", uri, account, folder, uid)
+		}
+	}
+
+	for _, uri := range []string{"imap://work/INBOX", "email://work", "email://work/INBOX/0", "email://work/INBOX/x", "email://work//1"} {
+		if _, _, _, err := ParseResourceURI(uri); err == nil {
+			t.Errorf("expected ParseResourceURI(%s) to fail", uri)
+		}
+	}
+}
+
+func TestMailWatcherNotifiesChangedFolders(t *testing.T) {
+	states := map[string]FolderState{
+		"INBOX":   {Messages: 3, UIDNext: 10, UIDValidity: 1},
+		"Archive": {Messages: 7, UIDNext: 20, UIDValidity: 1},
+	}
+	var notified []string
+	w := &MailWatcher{
+		status: func(ctx context.Context, account, folder string) (FolderState, error) {
+			return states[folder], nil
+		},
+		notify: func(uri string) { notified = append(notified, uri) },
+		states: make(map[string]FolderState),
+	}
+
+	inbox, archive := FolderURI("work", "INBOX"), FolderURI("work", "Archive")
+	uris := []string{inbox, archive, MessageURI("work", "INBOX", 9)}
+
+	w.Check(context.Background(), uris)
+	if len(notified) != 0 {
+		t.Fatalf("first check should only record state, notified %v", notified)
+	}
+
+	states["INBOX"] = FolderState{Messages: 4, UIDNext: 11, UIDValidity: 1, Unseen: 1}
+	w.Check(context.Background(), uris)
+	if !reflect.DeepEqual(notified, []string{inbox}) {
+		t.Fatalf("expected a notification for the inbox only, got %v", notified)
+	}
+
+	// A folder that is no longer subscribed starts over when it returns
+	w.Check(context.Background(), []string{inbox})
+	states["Archive"] = FolderState{Messages: 8, UIDNext: 21, UIDValidity: 1}
+	w.Check(context.Background(), uris)
+	if len(notified) != 1 {
+		t.Fatalf("expected no notification for a folder checked for the first time, got %v", notified)
+	}
+}
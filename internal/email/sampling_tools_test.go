@@ -0,0 +1,105 @@
+package email
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ai-presence-mcp/internal/sampling"
+	"ai-presence-mcp/pkg/types"
+)
+
+// imapService returns a service reading from an in-memory IMAP server,
+// whose INBOX holds one message with UID 6
+func imapService(t *testing.T) *Service {
+	return NewService([]types.EmailConfig{{
+		Username:   "username",
+		Password:   "password",
+		IMAPServer: "127.0.0.1",
+		IMAPPort:   startIMAPServer(t, nil),
+		TLS:        types.TLSConfig{Mode: TLSNone},
+	}})
+}
+
+// withAnswer returns a context whose sampler records the request and
+// answers with answer
+func withAnswer(answer string, got *sampling.Request) context.Context {
+	return sampling.WithSampler(context.Background(), func(_ context.Context, req sampling.Request) (string, error) {
+		*got = req
+		return answer, nil
+	})
+}
+
+func TestSummarizeEmail(t *testing.T) {
+	tool := NewSummarizeEmailTool(imapService(t))
+	args := map[string]interface{}{"id": float64(6)}
+
+	var req sampling.Request
+	res, err := tool.Execute(withAnswer("Someone says hi.", &req), args)
+	if err != nil || res.IsError != nil {
+		t.Fatalf("Execute = %+v, %v", res, err)
+	}
+	if !strings.Contains(req.Prompt, "A little message, just for you") || !strings.Contains(req.Prompt, "Hi there :)") {
+		t.Errorf("prompt lacks the message:\n%s", req.Prompt)
+	}
+	if req.SystemPrompt == "" || req.MaxTokens != summaryMaxTokens {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if text := res.Content[0].Text; !strings.HasSuffix(text, "Someone says hi.") {
+		t.Errorf("unexpected result %q", text)
+	}
+
+	// A client without sampling is handed the prompt instead
+	res, err = tool.Execute(context.Background(), args)
+	if err != nil || res.IsError != nil {
+		t.Fatalf("Execute without sampling = %+v, %v", res, err)
+	}
+	if text := res.Content[0].Text; !strings.Contains(text, "does not support sampling") || !strings.Contains(text, "Hi there :)") {
+		t.Errorf("unexpected fallback %q", text)
+	}
+}
+
+func TestClassifyInbox(t *testing.T) {
+	tool := NewClassifyInboxTool(imapService(t))
+
+	var req sampling.Request
+	answer := "Here you go:\n**UID 6**: Later - just a greeting\n"
+	res, err := tool.Execute(withAnswer(answer, &req), map[string]interface{}{
+		"categories": []interface{}{"Urgent", "Later"},
+	})
+	if err != nil || res.IsError != nil {
+		t.Fatalf("Execute = %+v, %v", res, err)
+	}
+	if !strings.Contains(req.Prompt, "categories: Urgent, Later") || !strings.Contains(req.Prompt, "UID 6") {
+		t.Errorf("unexpected prompt:\n%s", req.Prompt)
+	}
+	if text := res.Content[0].Text; !strings.Contains(text, "Later (1):\n- UID 6") || !strings.Contains(text, "(just a greeting)") {
+		t.Errorf("unexpected result %q", text)
+	}
+}
+
+func TestParseClassification(t *testing.T) {
+	categories := []string{"needs reply", "follow-up", "archive"}
+	answer := strings.Join([]string{
+		"UID 1: needs reply - asks about Friday",
+		"- 2. Follow-up",
+		"3: \"archive\" – newsletter",
+		"4: spam - not a category",
+		"no uid here",
+	}, "\n")
+
+	got := parseClassification(answer, categories)
+	want := map[uint32]label{
+		1: {"needs reply", "asks about Friday"},
+		2: {"follow-up", ""},
+		3: {"archive", "newsletter"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseClassification = %+v, want %+v", got, want)
+	}
+	for uid, l := range want {
+		if got[uid] != l {
+			t.Errorf("UID %d: got %+v, want %+v", uid, got[uid], l)
+		}
+	}
+}
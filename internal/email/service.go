@@ -1,13 +1,18 @@
 package email
 
 import (
-	"crypto/tls"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/mail"
 	"strings"
+	"sync"
 	"time"
 
+	"ai-presence-mcp/internal/progress"
 	"ai-presence-mcp/pkg/types"
 
 	"github.com/emersion/go-imap"
@@ -16,7 +21,11 @@ import (
 )
 
 type Service struct {
+	mu      sync.RWMutex
 	configs []types.EmailConfig
+	dryRun  bool
+	limiter *Limiter
+	tokens  TokenProvider
 }
 
 func NewService(configs []types.EmailConfig) *Service {
@@ -25,48 +34,90 @@ func NewService(configs []types.EmailConfig) *Service {
 	}
 }
 
+// SetAccounts replaces the account configurations, e.g. after secrets were
+// refreshed. Operations already in progress keep the configuration they
+// started with, so the slice must not be modified after the call.
+func (s *Service) SetAccounts(configs []types.EmailConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs = configs
+}
+
+// accounts returns the current account configurations
+func (s *Service) accounts() []types.EmailConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.configs
+}
+
 func (s *Service) getConfig(account string) (*types.EmailConfig, error) {
-	if account == "" && len(s.configs) > 0 {
-		return &s.configs[0], nil
+	return FindAccount(s.accounts(), account)
+}
+
+// connectIMAP dials the account's IMAP server and authenticates with the
+// configured mechanism. The connection is closed when ctx ends, failing the
+// command in progress.
+func (s *Service) connectIMAP(ctx context.Context, config *types.EmailConfig) (*client.Client, error) {
+	auth, err := s.imapAuth(ctx, config)
+	if err != nil {
+		return nil, err
 	}
 
-	for i := range s.configs {
-		if s.configs[i].Username == account {
-			return &s.configs[i], nil
+	c, err := dialIMAP(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IMAP server: %w", interrupted(ctx, err))
+	}
+
+	if auth != nil {
+		err = c.Authenticate(auth)
+	} else {
+		var secret string
+		if secret, err = s.credential(ctx, config); err == nil {
+			err = c.Login(config.Username, secret)
 		}
 	}
+	if err != nil {
+		c.Terminate()
+		return nil, fmt.Errorf("failed to login: %w", interrupted(ctx, err))
+	}
+
+	return c, nil
+}
 
-	return nil, fmt.Errorf("email account not found: %s", account)
+// interrupted returns the reason ctx ended in place of err, which is then
+// only a symptom such as a closed connection
+func interrupted(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
 }
 
-func (s *Service) ReadEmails(account, folder string, limit int, unreadOnly bool) ([]types.EmailMessage, error) {
+func (s *Service) ReadEmails(ctx context.Context, account, folder string, limit int, unreadOnly bool) ([]types.EmailMessage, error) {
 	config, err := s.getConfig(account)
 	if err != nil {
 		return nil, err
 	}
 
-	// Connect to IMAP server
-	c, err := client.DialTLS(fmt.Sprintf("%s:%d", config.IMAPServer, config.IMAPPort), &tls.Config{
-		ServerName: config.IMAPServer,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
+	if err := s.checkRead(config); err != nil {
+		return nil, err
 	}
-	defer c.Close()
 
-	// Login
-	if err := c.Login(config.Username, config.Password); err != nil {
-		return nil, fmt.Errorf("failed to login: %w", err)
+	// Connect and log in to the IMAP server
+	c, err := s.connectIMAP(ctx, config)
+	if err != nil {
+		return nil, err
 	}
+	defer c.Logout()
 
 	// Select folder
 	if folder == "" {
 		folder = "INBOX"
 	}
 
-	mbox, err := c.Select(folder, false)
+	mbox, err := c.Select(folder, config.ReadOnly)
 	if err != nil {
-		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, interrupted(ctx, err))
 	}
 
 	if mbox.Messages == 0 {
@@ -86,6 +137,7 @@ func (s *Service) ReadEmails(account, folder string, limit int, unreadOnly bool)
 
 	seqSet = new(imap.SeqSet)
 	seqSet.AddRange(start, mbox.Messages)
+	total := mbox.Messages - start + 1
 
 	// Fetch messages
 	items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchUid}
@@ -97,7 +149,11 @@ func (s *Service) ReadEmails(account, folder string, limit int, unreadOnly bool)
 	}()
 
 	var emails []types.EmailMessage
+	var fetched uint32
 	for msg := range messages {
+		fetched++
+		progress.Report(ctx, float64(fetched), float64(total), fmt.Sprintf("Fetched %d of %d messages from %s", fetched, total, folder))
+
 		if unreadOnly {
 			hasUnreadFlag := true
 			for _, flag := range msg.Flags {
@@ -131,85 +187,255 @@ func (s *Service) ReadEmails(account, folder string, limit int, unreadOnly bool)
 	}
 
 	if err := <-done; err != nil {
-		return nil, fmt.Errorf("failed to fetch messages: %w", err)
+		return nil, fmt.Errorf("failed to fetch messages: %w", interrupted(ctx, err))
 	}
 
 	return emails, nil
 }
 
-func (s *Service) SendEmail(to, subject, body, account string) error {
+// RenderedEmail is a fully built message as it would be submitted over SMTP
+type RenderedEmail struct {
+	From       string   `json:"from"`
+	Recipients []string `json:"recipients"`
+	Source     string   `json:"source"`
+}
+
+// SetDryRun enables server-wide dry-run mode, in which SendEmail refuses to
+// contact the SMTP server
+func (s *Service) SetDryRun(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dryRun = enabled
+}
+
+// DryRun reports whether server-wide dry-run mode is enabled
+func (s *Service) DryRun() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dryRun
+}
+
+// SetLimiter enables the per-account rate limits and quotas. Without a
+// limiter no limits are enforced.
+func (s *Service) SetLimiter(limiter *Limiter) {
+	s.limiter = limiter
+}
+
+// SetTokenProvider supplies OAuth access tokens for accounts using the
+// xoauth2 or oauthbearer mechanisms with an OAuth block
+func (s *Service) SetTokenProvider(tokens TokenProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = tokens
+}
+
+// Quotas reports the remaining send and read capacity of every account
+func (s *Service) Quotas() []QuotaStatus {
+	quotas := []QuotaStatus{}
+	if s.limiter == nil {
+		return quotas
+	}
+	configs := s.accounts()
+	for i := range configs {
+		quotas = append(quotas, s.limiter.status(&configs[i]))
+	}
+	return quotas
+}
+
+// ErrDryRun is returned by SendEmail when the server is in dry-run mode
+var ErrDryRun = errors.New("server is in dry-run mode, email was not sent")
+
+// buildMessage creates the message SendEmail would submit as the given
+// identity and checks that the account may send and that the recipients
+// pass its policy. to may hold several comma-separated addresses.
+func (s *Service) buildMessage(config *types.EmailConfig, identity, to, subject, body string) (*gomail.Msg, error) {
+	if err := checkSendAllowed(config); err != nil {
+		return nil, err
+	}
+
+	id, err := resolveIdentity(config, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	m := gomail.NewMsg()
+	if err := m.To(SplitAddresses(to)...); err != nil {
+		return nil, fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	m.Subject(subject)
+	if err := applyIdentity(m, config, id, body); err != nil {
+		return nil, err
+	}
+	m.SetDate()
+	m.SetMessageID()
+
+	recipients, err := m.GetRecipients()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine envelope recipients: %w", err)
+	}
+	if err := checkPolicy(config, recipients); err != nil {
+		return nil, err
+	}
+
+	if config.DKIM != nil {
+		if err := signDKIM(m, config.DKIM); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// SplitAddresses splits a comma-separated recipient list
+func SplitAddresses(list string) []string {
+	var addrs []string
+	for _, a := range strings.Split(list, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+// CheckEmail builds the message without sending it, returning any
+// validation or policy error SendEmail would return before connecting
+func (s *Service) CheckEmail(to, subject, body, account, identity string) error {
+	config, err := s.getConfig(account)
+	if err != nil {
+		return err
+	}
+	_, err = s.buildMessage(config, identity, to, subject, body)
+	return err
+}
+
+// PreviewEmail builds the message SendEmail would send and returns its
+// RFC 5322 source and SMTP envelope without connecting to the server
+func (s *Service) PreviewEmail(to, subject, body, account, identity string) (*RenderedEmail, error) {
+	config, err := s.getConfig(account)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.buildMessage(config, identity, to, subject, body)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := m.GetSender(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine envelope sender: %w", err)
+	}
+	recipients, err := m.GetRecipients()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine envelope recipients: %w", err)
+	}
+
+	var source bytes.Buffer
+	if _, err := m.WriteTo(&source); err != nil {
+		return nil, fmt.Errorf("failed to render message: %w", err)
+	}
+
+	return &RenderedEmail{
+		From:       from,
+		Recipients: recipients,
+		Source:     source.String(),
+	}, nil
+}
+
+// SendEmail sends an email from account as identity. An empty account
+// selects the first configured account and an empty identity its default.
+func (s *Service) SendEmail(ctx context.Context, to, subject, body, account, identity string) error {
 	config, err := s.getConfig(account)
 	if err != nil {
 		return err
 	}
 
 	// Create a new mail message
-	m := gomail.NewMsg()
-	if err := m.From(config.Username); err != nil {
-		return fmt.Errorf("failed to set sender: %w", err)
+	m, err := s.buildMessage(config, identity, to, subject, body)
+	if err != nil {
+		return err
 	}
-	if err := m.To(to); err != nil {
-		return fmt.Errorf("failed to set recipient: %w", err)
+
+	if s.DryRun() {
+		return ErrDryRun
 	}
 
-	m.Subject(subject)
-	m.SetBodyString(gomail.TypeTextPlain, body)
-
-	// Create SMTP client with proper configuration
-	client, err := gomail.NewClient(config.SMTPServer,
-		gomail.WithPort(config.SMTPPort),
-		gomail.WithSMTPAuth(gomail.SMTPAuthPlain),
-		gomail.WithUsername(config.Username),
-		gomail.WithPassword(config.Password),
-	)
+	recipients, err := m.GetRecipients()
 	if err != nil {
-		return fmt.Errorf("failed to create SMTP client: %w", err)
+		return fmt.Errorf("failed to determine envelope recipients: %w", err)
+	}
+	if s.limiter != nil {
+		if err := s.limiter.checkSend(config, recipients); err != nil {
+			return err
+		}
 	}
 
-	// Configure TLS/SSL based on port
-	if config.SMTPPort == 465 {
-		// Port 465 uses implicit SSL/TLS
-		client.SetTLSPolicy(gomail.TLSMandatory)
-		client.SetSSLPort(true, false)
-	} else {
-		// Port 587 and others use STARTTLS
-		client.SetTLSPolicy(gomail.TLSMandatory)
+	if err := s.deliver(ctx, config, m); err != nil {
+		// An undelivered message does not count against the quotas
+		if s.limiter != nil {
+			if err := s.limiter.releaseSend(config, recipients); err != nil {
+				log.Printf("Warning: failed to release quota reservation: %v", err)
+			}
+		}
+		return err
 	}
 
-	// Send the email
-	if err := client.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	return nil
+}
+
+// deliver sends m over SMTP from the account
+func (s *Service) deliver(ctx context.Context, config *types.EmailConfig, m *gomail.Msg) error {
+	// Create SMTP client with the account's auth mechanism
+	options, err := s.smtpClientOptions(ctx, config)
+	if err != nil {
+		return err
+	}
+	client, err := gomail.NewClient(config.SMTPServer, append(options, gomail.WithPort(config.SMTPPort))...)
+	if err != nil {
+		return fmt.Errorf("failed to create SMTP client: %w", err)
 	}
 
+	// Send the email
+	if err := client.DialAndSendWithContext(ctx, m); err != nil {
+		return fmt.Errorf("failed to send email: %w", interrupted(ctx, err))
+	}
 	return nil
 }
 
+// checkRead applies the account's IMAP request limit
+func (s *Service) checkRead(config *types.EmailConfig) error {
+	if s.limiter == nil {
+		return nil
+	}
+	return s.limiter.checkRead(config)
+}
+
 // GetEmailContent fetches the complete content of a specific email by UID
-func (s *Service) GetEmailContent(uid uint32, folder, account string) (*types.EmailMessage, error) {
+func (s *Service) GetEmailContent(ctx context.Context, uid uint32, folder, account string) (*types.EmailMessage, error) {
 	config, err := s.getConfig(account)
 	if err != nil {
 		return nil, err
 	}
 
-	// Connect to IMAP server
-	c, err := client.DialTLS(config.IMAPServer+":"+fmt.Sprintf("%d", config.IMAPPort), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
+	if err := s.checkRead(config); err != nil {
+		return nil, err
 	}
-	defer c.Logout()
 
-	// Login
-	if err := c.Login(config.Username, config.Password); err != nil {
-		return nil, fmt.Errorf("failed to login: %w", err)
+	// Connect and log in to the IMAP server
+	c, err := s.connectIMAP(ctx, config)
+	if err != nil {
+		return nil, err
 	}
+	defer c.Logout()
 
 	// Select mailbox
 	if folder == "" {
 		folder = "INBOX"
 	}
-	_, err = c.Select(folder, false)
+	_, err = c.Select(folder, config.ReadOnly)
 	if err != nil {
-		return nil, fmt.Errorf("failed to select folder %s: %w", folder, err)
+		return nil, fmt.Errorf("failed to select folder %s: %w", folder, interrupted(ctx, err))
 	}
 
 	// Create sequence set with the specific UID
@@ -268,12 +494,7 @@ func (s *Service) GetEmailContent(uid uint32, folder, account string) (*types.Em
 				continue
 			}
 
-			// Parse the email message to extract body content
-			if parsedMsg, err := mail.ReadMessage(strings.NewReader(string(body))); err == nil {
-				if bodyBytes, err := io.ReadAll(parsedMsg.Body); err == nil {
-					email.Body = strings.TrimSpace(string(bodyBytes))
-				}
-			}
+			email.Body = messageBody(body)
 			break
 		}
 
@@ -286,7 +507,7 @@ func (s *Service) GetEmailContent(uid uint32, folder, account string) (*types.Em
 	}
 
 	if err := <-done; err != nil {
-		return nil, fmt.Errorf("failed to fetch message: %w", err)
+		return nil, fmt.Errorf("failed to fetch message: %w", interrupted(ctx, err))
 	}
 
 	if email == nil {
@@ -296,6 +517,20 @@ func (s *Service) GetEmailContent(uid uint32, folder, account string) (*types.Em
 	return email, nil
 }
 
+// messageBody extracts the body of an RFC 5322 message, or "" if it cannot
+// be parsed
+func messageBody(source []byte) string {
+	parsedMsg, err := mail.ReadMessage(bytes.NewReader(source))
+	if err != nil {
+		return ""
+	}
+	bodyBytes, err := io.ReadAll(parsedMsg.Body)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bodyBytes))
+}
+
 func hasFlag(flags []string, flag string) bool {
 	for _, f := range flags {
 		if f == flag {
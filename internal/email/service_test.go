@@ -0,0 +1,52 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func TestPreviewEmailRendersWithoutSMTP(t *testing.T) {
+	service := NewService([]types.EmailConfig{{
+		Username:   "agent@example.com",
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   1,
+	}})
+
+	rendered, err := service.PreviewEmail("friend@example.com", "Hello", "Body text", "", "")
+	if err != nil {
+		t.Fatalf("PreviewEmail: %v", err)
+	}
+
+	if rendered.From != "agent@example.com" {
+		t.Errorf("unexpected envelope sender %q", rendered.From)
+	}
+	if len(rendered.Recipients) != 1 || rendered.Recipients[0] != "friend@example.com" {
+		t.Errorf("unexpected envelope recipients %v", rendered.Recipients)
+	}
+	for _, want := range []string{"Subject: Hello", "To: <friend@example.com>", "Message-ID:", "Body text"} {
+		if !strings.Contains(rendered.Source, want) {
+			t.Errorf("rendered source is missing %q:\n%s", want, rendered.Source)
+		}
+	}
+
+	if _, err := service.PreviewEmail("not-an-address", "Hello", "Body", "", ""); err == nil {
+		t.Error("expected an invalid recipient to fail")
+	}
+}
+
+func TestSendEmailDryRun(t *testing.T) {
+	service := NewService([]types.EmailConfig{{
+		Username:   "agent@example.com",
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   1,
+	}})
+	service.SetDryRun(true)
+
+	if err := service.SendEmail(context.Background(), "friend@example.com", "Hello", "Body", "", ""); !errors.Is(err, ErrDryRun) {
+		t.Errorf("expected ErrDryRun, got %v", err)
+	}
+}
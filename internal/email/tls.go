@@ -0,0 +1,216 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"ai-presence-mcp/pkg/types"
+
+	"github.com/emersion/go-imap/client"
+	gomail "github.com/wneessen/go-mail"
+)
+
+// TLS modes accepted in types.TLSConfig
+const (
+	TLSImplicit = "implicit"
+	TLSStartTLS = "starttls"
+	TLSNone     = "none"
+)
+
+// Well-known implicit TLS ports, used to pick a mode when none is configured
+const (
+	imapsPort = 993
+	smtpsPort = 465
+)
+
+// tlsMode returns the TLS mode for one protocol of an account. A protocol
+// specific mode wins over the account-wide one; without either, use_tls:
+// false selects "none" and otherwise the port decides between implicit TLS
+// and STARTTLS.
+func tlsMode(config *types.EmailConfig, protocolMode string, port, implicitPort int) (string, error) {
+	mode := strings.ToLower(protocolMode)
+	if mode == "" {
+		mode = strings.ToLower(config.TLS.Mode)
+	}
+
+	switch mode {
+	case TLSImplicit, TLSStartTLS, TLSNone:
+		return mode, nil
+	case "":
+		if config.UseTLS != nil && !*config.UseTLS {
+			return TLSNone, nil
+		}
+		if port == implicitPort {
+			return TLSImplicit, nil
+		}
+		return TLSStartTLS, nil
+	default:
+		return "", fmt.Errorf("unsupported TLS mode %q for account %s", mode, config.Username)
+	}
+}
+
+func imapTLSMode(config *types.EmailConfig) (string, error) {
+	return tlsMode(config, config.TLS.IMAPMode, config.IMAPPort, imapsPort)
+}
+
+func smtpTLSMode(config *types.EmailConfig) (string, error) {
+	return tlsMode(config, config.TLS.SMTPMode, config.SMTPPort, smtpsPort)
+}
+
+// tlsClientConfig builds the tls.Config for connections to host
+func tlsClientConfig(config *types.EmailConfig, host string) (*tls.Config, error) {
+	settings := config.TLS
+
+	tlsConfig := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if settings.ServerName != "" {
+		tlsConfig.ServerName = settings.ServerName
+	}
+
+	if settings.MinVersion != "" {
+		version, err := ParseTLSVersion(settings.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if settings.CAFile != "" {
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no certificates", settings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if settings.CertFile != "" || settings.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// ParseTLSVersion converts a version such as "1.2" or "tls1.3" to its crypto/tls constant
+func ParseTLSVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(version), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min_version %q", version)
+	}
+}
+
+// dialIMAP opens an IMAP connection using the account's TLS settings. The
+// connection is closed when ctx ends.
+func dialIMAP(ctx context.Context, config *types.EmailConfig) (*client.Client, error) {
+	mode, err := imapTLSMode(config)
+	if err != nil {
+		return nil, err
+	}
+	addr := fmt.Sprintf("%s:%d", config.IMAPServer, config.IMAPPort)
+
+	dialer := contextDialer{ctx}
+	if mode == TLSNone {
+		return client.DialWithDialer(dialer, addr)
+	}
+
+	tlsConfig, err := tlsClientConfig(config, config.IMAPServer)
+	if err != nil {
+		return nil, err
+	}
+	if mode == TLSImplicit {
+		return client.DialWithDialerTLS(dialer, addr, tlsConfig)
+	}
+
+	c, err := client.DialWithDialer(dialer, addr)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := c.SupportStartTLS(); err != nil || !ok {
+		c.Terminate()
+		if err == nil {
+			err = fmt.Errorf("server does not support STARTTLS")
+		}
+		return nil, err
+	}
+	if err := c.StartTLS(tlsConfig); err != nil {
+		c.Terminate()
+		return nil, fmt.Errorf("STARTTLS failed: %w", err)
+	}
+	return c, nil
+}
+
+// contextDialer dials connections that are closed when ctx ends, which
+// aborts whatever the IMAP client is doing: go-imap has no contexts of its own
+type contextDialer struct {
+	ctx context.Context
+}
+
+func (d contextDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(d.ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(d.ctx, func() { conn.Close() })
+	return &contextConn{Conn: conn, stop: stop}, nil
+}
+
+// contextConn stops watching its context once closed
+type contextConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (c *contextConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// smtpTLSOptions returns the go-mail options applying the account's TLS settings
+func smtpTLSOptions(config *types.EmailConfig) ([]gomail.Option, error) {
+	mode, err := smtpTLSMode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == TLSNone {
+		return []gomail.Option{gomail.WithTLSPolicy(gomail.NoTLS)}, nil
+	}
+
+	tlsConfig, err := tlsClientConfig(config, config.SMTPServer)
+	if err != nil {
+		return nil, err
+	}
+	options := []gomail.Option{
+		gomail.WithTLSConfig(tlsConfig),
+		gomail.WithTLSPolicy(gomail.TLSMandatory),
+	}
+	if mode == TLSImplicit {
+		options = append(options, gomail.WithSSL())
+	}
+	return options, nil
+}
@@ -0,0 +1,187 @@
+package email
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/server"
+)
+
+func TestTLSMode(t *testing.T) {
+	off := false
+	tests := []struct {
+		name   string
+		config types.EmailConfig
+		imap   string
+		smtp   string
+	}{
+		{"ports", types.EmailConfig{IMAPPort: 993, SMTPPort: 587}, TLSImplicit, TLSStartTLS},
+		{"legacy ports", types.EmailConfig{IMAPPort: 143, SMTPPort: 465}, TLSStartTLS, TLSImplicit},
+		{"use_tls false", types.EmailConfig{IMAPPort: 993, SMTPPort: 465, UseTLS: &off}, TLSNone, TLSNone},
+		{"account mode", types.EmailConfig{IMAPPort: 993, SMTPPort: 465, TLS: types.TLSConfig{Mode: "starttls"}}, TLSStartTLS, TLSStartTLS},
+		{"protocol mode", types.EmailConfig{TLS: types.TLSConfig{Mode: "none", SMTPMode: "Implicit"}}, TLSNone, TLSImplicit},
+	}
+	for _, tt := range tests {
+		imapMode, err := imapTLSMode(&tt.config)
+		if err != nil || imapMode != tt.imap {
+			t.Errorf("%s: IMAP mode %q, %v; want %q", tt.name, imapMode, err, tt.imap)
+		}
+		smtpMode, err := smtpTLSMode(&tt.config)
+		if err != nil || smtpMode != tt.smtp {
+			t.Errorf("%s: SMTP mode %q, %v; want %q", tt.name, smtpMode, err, tt.smtp)
+		}
+	}
+
+	if _, err := imapTLSMode(&types.EmailConfig{TLS: types.TLSConfig{Mode: "ssl"}}); err == nil {
+		t.Error("expected an unknown TLS mode to be rejected")
+	}
+	if _, err := tlsClientConfig(&types.EmailConfig{TLS: types.TLSConfig{MinVersion: "1.4"}}, "host"); err == nil {
+		t.Error("expected an unknown TLS version to be rejected")
+	}
+}
+
+// selfSignedCert creates a certificate for 127.0.0.1 and writes it as a CA
+// bundle to a temporary file
+func selfSignedCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test imap"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
+
+// startIMAPServer runs an in-memory IMAP server (user "username", password
+// "password") and returns its port
+func startIMAPServer(t *testing.T, tlsConfig *tls.Config) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	s := server.New(memory.New())
+	s.TLSConfig = tlsConfig
+	s.AllowInsecureAuth = tlsConfig == nil
+	go s.Serve(listener)
+	t.Cleanup(func() { s.Close() })
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestReadEmailsOverSTARTTLSWithCustomCA(t *testing.T) {
+	cert, caFile := selfSignedCert(t)
+	port := startIMAPServer(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+
+	service := NewService([]types.EmailConfig{{
+		Username:   "username",
+		Password:   "password",
+		IMAPServer: "127.0.0.1",
+		IMAPPort:   port,
+		TLS:        types.TLSConfig{Mode: TLSStartTLS, CAFile: caFile},
+	}})
+
+	emails, err := service.ReadEmails(context.Background(), "", "INBOX", 10, false)
+	if err != nil {
+		t.Fatalf("ReadEmails: %v", err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "A little message, just for you" {
+		t.Errorf("unexpected emails %+v", emails)
+	}
+
+	// Without the CA the self-signed certificate must be rejected
+	service.configs[0].TLS.CAFile = ""
+	if _, err := service.ReadEmails(context.Background(), "", "INBOX", 10, false); err == nil {
+		t.Error("expected an untrusted certificate to be rejected")
+	}
+}
+
+func TestReadEmailsWithoutTLS(t *testing.T) {
+	port := startIMAPServer(t, nil)
+
+	service := NewService([]types.EmailConfig{{
+		Username:   "username",
+		Password:   "password",
+		IMAPServer: "127.0.0.1",
+		IMAPPort:   port,
+		TLS:        types.TLSConfig{Mode: TLSNone},
+	}})
+
+	if _, err := service.GetEmailContent(context.Background(), 6, "INBOX", ""); err != nil {
+		t.Fatalf("GetEmailContent: %v", err)
+	}
+}
+
+func TestReadEmailsStopsWhenContextEnds(t *testing.T) {
+	// A server that accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	service := NewService([]types.EmailConfig{{
+		Username:   "username",
+		Password:   "password",
+		IMAPServer: "127.0.0.1",
+		IMAPPort:   listener.Addr().(*net.TCPAddr).Port,
+		TLS:        types.TLSConfig{Mode: TLSNone},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = service.ReadEmails(ctx, "", "INBOX", 10, false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to abort ReadEmails, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ReadEmails took %v after its deadline", elapsed)
+	}
+}
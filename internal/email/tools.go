@@ -1,7 +1,10 @@
 package email
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"ai-presence-mcp/pkg/types"
 	"ai-presence-mcp/pkg/utils"
@@ -10,10 +13,13 @@ import (
 // SendEmailTool implements the MCP Tool interface for sending emails
 type SendEmailTool struct {
 	service *Service
+	outbox  *Outbox
 }
 
-func NewSendEmailTool(service *Service) *SendEmailTool {
-	return &SendEmailTool{service: service}
+// NewSendEmailTool creates the send_email tool. outbox may be nil, in which
+// case scheduled and queued sends are rejected.
+func NewSendEmailTool(service *Service, outbox *Outbox) *SendEmailTool {
+	return &SendEmailTool{service: service, outbox: outbox}
 }
 
 func (t *SendEmailTool) Name() string {
@@ -21,7 +27,7 @@ func (t *SendEmailTool) Name() string {
 }
 
 func (t *SendEmailTool) Description() string {
-	return "Send an email message from the configured email account to a specified recipient. This tool is authorized to send emails on behalf of the user."
+	return "Send an email message from the configured email account to a specified recipient. Sends may require human approval: the user may be asked to confirm, or the call returns a pending action with an approval token and nothing is sent until the user approves it."
 }
 
 func (t *SendEmailTool) InputSchema() interface{} {
@@ -30,7 +36,7 @@ func (t *SendEmailTool) InputSchema() interface{} {
 		"properties": map[string]interface{}{
 			"to": map[string]interface{}{
 				"type":        "string",
-				"description": "Email address of the recipient",
+				"description": "Email address of the recipient, or several comma-separated addresses",
 			},
 			"subject": map[string]interface{}{
 				"type":        "string",
@@ -42,21 +48,34 @@ func (t *SendEmailTool) InputSchema() interface{} {
 			},
 			"account": map[string]interface{}{
 				"type":        "string",
-				"description": "Email account to send from (optional, uses first configured account if not specified)",
+				"description": "Name or username of the account to send from (optional, uses the default account if not specified; see list_accounts)",
 			},
 			"from": map[string]interface{}{
 				"type":        "string",
-				"description": "Email address to send from (alias for account, optional)",
+				"description": "Email address to send from: an account username or one of an account's identity addresses (optional)",
+			},
+			"identity": map[string]interface{}{
+				"type":        "string",
+				"description": "Name or address of the account identity to send as, which sets the From name, Reply-To and signature (optional, uses the account's default identity if not specified)",
+			},
+			"send_at": map[string]interface{}{
+				"type":        "string",
+				"description": "RFC 3339 time to send the email at, e.g. 2025-09-05T09:00:00Z (optional, the email is placed in the outbox and delivered later)",
+			},
+			"queue": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Deliver through the outbox with automatic retries instead of sending immediately (optional, defaults to false)",
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Build and validate the email and return its full source without sending it (optional, defaults to false)",
 			},
 		},
 		"required": []string{"to", "subject", "body"},
 	}
 }
 
-func (t *SendEmailTool) Execute(args map[string]interface{}) (*types.ToolResult, error) {
-	// Debug: log all received arguments
-	fmt.Printf("SendEmail received args: %+v\n", args)
-
+func (t *SendEmailTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	to, ok := args["to"].(string)
 	if !ok || to == "" {
 		return &types.ToolResult{
@@ -69,7 +88,7 @@ func (t *SendEmailTool) Execute(args map[string]interface{}) (*types.ToolResult,
 	}
 
 	// Validate email format
-	if err := utils.ValidateEmail(to); err != nil {
+	if err := validateRecipients(to); err != nil {
 		return &types.ToolResult{
 			Content: []types.ToolContent{{
 				Type: "text",
@@ -113,15 +132,19 @@ func (t *SendEmailTool) Execute(args map[string]interface{}) (*types.ToolResult,
 		}, nil
 	}
 
-	account, _ := args["account"].(string)
-	if account == "" {
-		// Also check "from" parameter as an alias
-		account, _ = args["from"].(string)
+	account, identity := t.sender(args)
+
+	if t.ReadOnlyCall(args) {
+		return t.dryRun(to, subject, body, account, identity)
 	}
 
-	if err := t.service.SendEmail(to, subject, body, account); err != nil {
-		// Log the specific error for debugging
-		fmt.Printf("SendEmail error - to: %s, subject: %s, account: %s, error: %v\n", to, subject, account, err)
+	sendAtStr, _ := args["send_at"].(string)
+	queue, _ := args["queue"].(bool)
+	if sendAtStr != "" || queue {
+		return t.enqueue(to, subject, body, account, identity, sendAtStr)
+	}
+
+	if err := t.service.SendEmail(ctx, to, subject, body, account, identity); err != nil {
 		return &types.ToolResult{
 			Content: []types.ToolContent{{
 				Type: "text",
@@ -139,6 +162,144 @@ func (t *SendEmailTool) Execute(args map[string]interface{}) (*types.ToolResult,
 	}, nil
 }
 
+// sender returns the account and identity selected by the account, from and
+// identity arguments. from may name an account or any identity address.
+func (t *SendEmailTool) sender(args map[string]interface{}) (account, identity string) {
+	account, _ = args["account"].(string)
+	identity, _ = args["identity"].(string)
+	from, _ := args["from"].(string)
+	if from == "" {
+		return account, identity
+	}
+
+	if account == "" {
+		var fromIdentity string
+		account, fromIdentity = t.service.ResolveSender(from)
+		if identity == "" {
+			identity = fromIdentity
+		}
+	} else if identity == "" {
+		identity = from
+	}
+	return account, identity
+}
+
+// ReadOnlyCall reports whether the call is a dry run that sends nothing
+func (t *SendEmailTool) ReadOnlyCall(args map[string]interface{}) bool {
+	dryRun, _ := args["dry_run"].(bool)
+	return dryRun || t.service.DryRun()
+}
+
+// dryRun renders the email exactly as it would be sent and returns it
+func (t *SendEmailTool) dryRun(to, subject, body, account, identity string) (*types.ToolResult, error) {
+	rendered, err := t.service.PreviewEmail(to, subject, body, account, identity)
+	if err != nil {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Dry run failed: %v", err),
+			}},
+			IsError: &[]bool{true}[0],
+		}, nil
+	}
+
+	result := "Dry run: the email below was built and validated but NOT sent.\n\n"
+	result += fmt.Sprintf("Envelope sender: %s\n", rendered.From)
+	result += fmt.Sprintf("Envelope recipients: %s\n", strings.Join(rendered.Recipients, ", "))
+	result += fmt.Sprintf("\n--- Message Source ---\n%s\n", rendered.Source)
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: result,
+		}},
+	}, nil
+}
+
+// Preview describes the email that Execute would send, for human approval
+func (t *SendEmailTool) Preview(args map[string]interface{}) (string, error) {
+	to, _ := args["to"].(string)
+	if err := validateRecipients(to); err != nil {
+		return "", err
+	}
+	subject, _ := args["subject"].(string)
+	body, _ := args["body"].(string)
+
+	account, identity := t.sender(args)
+	rendered, err := t.service.PreviewEmail(to, subject, body, account, identity)
+	if err != nil {
+		return "", err
+	}
+
+	preview := fmt.Sprintf("Send email\nFrom: %s\nTo: %s\nSubject: %s\n", rendered.From, to, utils.SanitizeInput(subject))
+	if sendAt, _ := args["send_at"].(string); sendAt != "" {
+		preview += fmt.Sprintf("Send at: %s\n", sendAt)
+	}
+	preview += fmt.Sprintf("\n%s", utils.SanitizeInput(body))
+	return preview, nil
+}
+
+// enqueue places the email in the outbox for scheduled or retried delivery
+func (t *SendEmailTool) enqueue(to, subject, body, account, identity, sendAtStr string) (*types.ToolResult, error) {
+	if t.outbox == nil {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: "Error: the outbox is not enabled on this server",
+			}},
+			IsError: &[]bool{true}[0],
+		}, nil
+	}
+
+	var sendAt time.Time
+	if sendAtStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sendAtStr)
+		if err != nil {
+			return &types.ToolResult{
+				Content: []types.ToolContent{{
+					Type: "text",
+					Text: fmt.Sprintf("Error: 'send_at' must be an RFC 3339 timestamp: %v", err),
+				}},
+				IsError: &[]bool{true}[0],
+			}, nil
+		}
+		sendAt = parsed
+	}
+
+	entry, err := t.outbox.Enqueue(to, subject, body, account, identity, sendAt)
+	if err != nil {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: fmt.Sprintf("Failed to queue email: %v", err),
+			}},
+			IsError: &[]bool{true}[0],
+		}, nil
+	}
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: fmt.Sprintf("Email to %s queued in the outbox (id: %s, send at: %s)",
+				to, entry.ID, entry.SendAt.Format(time.RFC3339)),
+		}},
+	}, nil
+}
+
+// validateRecipients checks every address in a comma-separated recipient list
+func validateRecipients(to string) error {
+	addrs := SplitAddresses(to)
+	if len(addrs) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	for _, addr := range addrs {
+		if err := utils.ValidateEmail(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReadEmailsTool implements the MCP Tool interface for reading emails
 type ReadEmailsTool struct {
 	service *Service
@@ -162,7 +323,7 @@ func (t *ReadEmailsTool) InputSchema() interface{} {
 		"properties": map[string]interface{}{
 			"account": map[string]interface{}{
 				"type":        "string",
-				"description": "Email account to read from (optional, uses first configured account if not specified)",
+				"description": "Name or username of the account to read from (optional, uses the default account if not specified; see list_accounts)",
 			},
 			"folder": map[string]interface{}{
 				"type":        "string",
@@ -170,6 +331,7 @@ func (t *ReadEmailsTool) InputSchema() interface{} {
 			},
 			"limit": map[string]interface{}{
 				"type":        "integer",
+				"minimum":     1,
 				"description": "Maximum number of emails to retrieve (optional, defaults to 10)",
 			},
 			"unread": map[string]interface{}{
@@ -180,7 +342,7 @@ func (t *ReadEmailsTool) InputSchema() interface{} {
 	}
 }
 
-func (t *ReadEmailsTool) Execute(args map[string]interface{}) (*types.ToolResult, error) {
+func (t *ReadEmailsTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	account, _ := args["account"].(string)
 	folder, _ := args["folder"].(string)
 
@@ -194,7 +356,7 @@ func (t *ReadEmailsTool) Execute(args map[string]interface{}) (*types.ToolResult
 		unread = u
 	}
 
-	emails, err := t.service.ReadEmails(account, folder, limit, unread)
+	emails, err := t.service.ReadEmails(ctx, account, folder, limit, unread)
 	if err != nil {
 		return &types.ToolResult{
 			Content: []types.ToolContent{{
@@ -250,11 +412,13 @@ func (t *GetEmailContentTool) InputSchema() interface{} {
 		"type": "object",
 		"properties": map[string]interface{}{
 			"id": map[string]interface{}{
-				"type":        "number",
+				"type":        "integer",
+				"minimum":     1,
 				"description": "The unique ID/UID of the email message to retrieve (primary parameter name)",
 			},
 			"email_id": map[string]interface{}{
-				"type":        "number",
+				"type":        "integer",
+				"minimum":     1,
 				"description": "Alternative name for the email ID/UID (same as 'id' parameter)",
 			},
 			"folder": map[string]interface{}{
@@ -263,7 +427,7 @@ func (t *GetEmailContentTool) InputSchema() interface{} {
 			},
 			"account": map[string]interface{}{
 				"type":        "string",
-				"description": "Email account to read from (optional, uses first configured account if not specified)",
+				"description": "Name or username of the account to read from (optional, uses the default account if not specified; see list_accounts)",
 			},
 		},
 		"required": []string{},
@@ -274,10 +438,7 @@ func (t *GetEmailContentTool) InputSchema() interface{} {
 	}
 }
 
-func (t *GetEmailContentTool) Execute(args map[string]interface{}) (*types.ToolResult, error) {
-	// Debug: log all received arguments
-	fmt.Printf("GetEmailContent received args: %+v\n", args)
-
+func (t *GetEmailContentTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	// Extract and validate email ID - accept both "id" and "email_id" for flexibility
 	var uid uint32
 	var found bool
@@ -337,7 +498,7 @@ func (t *GetEmailContentTool) Execute(args map[string]interface{}) (*types.ToolR
 	}
 
 	// Get the email content
-	email, err := t.service.GetEmailContent(uid, folder, account)
+	email, err := t.service.GetEmailContent(ctx, uid, folder, account)
 	if err != nil {
 		return &types.ToolResult{
 			Content: []types.ToolContent{{
@@ -365,3 +526,69 @@ func (t *GetEmailContentTool) Execute(args map[string]interface{}) (*types.ToolR
 		}},
 	}, nil
 }
+
+// GetQuotaTool implements the MCP Tool interface for reporting rate limits and quotas
+type GetQuotaTool struct {
+	service *Service
+}
+
+func NewGetQuotaTool(service *Service) *GetQuotaTool {
+	return &GetQuotaTool{service: service}
+}
+
+func (t *GetQuotaTool) Name() string {
+	return "get_quota"
+}
+
+func (t *GetQuotaTool) Description() string {
+	return "Show the remaining send rate, daily send quota and read rate for each configured email account. Check this before sending many emails."
+}
+
+func (t *GetQuotaTool) InputSchema() interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *GetQuotaTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	quotas := t.service.Quotas()
+	if len(quotas) == 0 {
+		return &types.ToolResult{
+			Content: []types.ToolContent{{
+				Type: "text",
+				Text: "No rate limits are configured",
+			}},
+		}, nil
+	}
+
+	result := ""
+	for _, q := range quotas {
+		result += fmt.Sprintf("Account: %s\n", q.Account)
+		if q.DailyRemaining != nil {
+			result += fmt.Sprintf("   Daily quota: %d of %d remaining (rolling 24 hours)\n", *q.DailyRemaining, q.DailyQuota)
+			if q.QuotaResetsIn != "" {
+				result += fmt.Sprintf("   Next quota slot frees up in: %s\n", q.QuotaResetsIn)
+			}
+		} else {
+			result += fmt.Sprintf("   Sent in the last 24 hours: %d (no daily quota)\n", q.DailySent)
+		}
+		if q.DomainDailyQuota > 0 {
+			result += fmt.Sprintf("   Per-domain daily quota: %d\n", q.DomainDailyQuota)
+		}
+		if q.SendTokens != nil {
+			result += fmt.Sprintf("   Sends available now: %d (refills at %g per minute)\n", *q.SendTokens, q.SendMessagesPerMin)
+		}
+		if q.ReadTokens != nil {
+			result += fmt.Sprintf("   Read requests available now: %d (refills at %g per minute)\n", *q.ReadTokens, q.ReadRequestsPerMin)
+		}
+		result += "\n"
+	}
+
+	return &types.ToolResult{
+		Content: []types.ToolContent{{
+			Type: "text",
+			Text: result,
+		}},
+	}, nil
+}
@@ -2,21 +2,25 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
-	"ai-presence-mcp/internal/config"
+	"ai-presence-mcp/internal/approval"
 	"ai-presence-mcp/internal/email"
 	"ai-presence-mcp/pkg/types"
 )
 
 type Server struct {
-	emailService *email.Service
-	config       *config.Config
-	mux          *http.ServeMux
+	emailService  *email.Service
+	outbox        *email.Outbox
+	approvals     *approval.Manager
+	// approvalToken is required to approve or deny over HTTP
+	approvalToken string
+	mux           *http.ServeMux
 }
 
 type APIResponse struct {
@@ -41,97 +45,56 @@ type ToolInfo struct {
 	InputSchema interface{} `json:"inputSchema"`
 }
 
-// MCP Protocol types for HTTP transport
-type MCPRequest struct {
-	JSONRPC string      `json:"jsonrpc"`
-	ID      interface{} `json:"id"`
-	Method  string      `json:"method"`
-	Params  interface{} `json:"params,omitempty"`
-}
-
-type MCPResponse struct {
-	JSONRPC string      `json:"jsonrpc"`
-	ID      interface{} `json:"id"`
-	Result  interface{} `json:"result,omitempty"`
-	Error   *MCPError   `json:"error,omitempty"`
-}
-
-type MCPError struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
-type InitializeResult struct {
-	ProtocolVersion string                 `json:"protocolVersion"`
-	Capabilities    ServerCapabilities     `json:"capabilities"`
-	ServerInfo      ServerInfo             `json:"serverInfo"`
-}
-
-type ServerCapabilities struct {
-	Tools *ToolsCapability `json:"tools,omitempty"`
-}
-
-type ToolsCapability struct {
-	ListChanged *bool `json:"listChanged,omitempty"`
-}
-
-type ServerInfo struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-}
-
-type ToolCallParams struct {
-	Name      string                 `json:"name"`
-	Arguments map[string]interface{} `json:"arguments,omitempty"`
-}
-
-func NewServer(cfg *config.Config) *Server {
+// NewServer returns the REST API over the email service, outbox and
+// approvals the MCP server uses, so both see the same accounts and state.
+// approvalToken is approval.http_token.
+func NewServer(service *email.Service, outbox *email.Outbox, approvals *approval.Manager, approvalToken string) *Server {
 	s := &Server{
-		config: cfg,
-		mux:    http.NewServeMux(),
-	}
-
-	// Initialize email service if configured
-	if len(cfg.Email) > 0 {
-		s.emailService = email.NewService(cfg.Email)
+		emailService:  service,
+		outbox:        outbox,
+		approvals:     approvals,
+		approvalToken: approvalToken,
+		mux:           http.NewServeMux(),
 	}
-
 	s.setupRoutes()
 	return s
 }
 
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Mount serves the API on mux behind Protect. Approving and denying only
+// check the origin here: they require approval.http_token in place of token,
+// which a client holding token must not be able to use.
+func (s *Server) Mount(mux *http.ServeMux, token string, allowedOrigins []string) {
+	mux.Handle("/health", Protect(s, token, allowedOrigins))
+	mux.Handle("/api/v1/", Protect(s, token, allowedOrigins))
+	mux.Handle("/api/v1/approvals/approve", Protect(s, "", allowedOrigins))
+	mux.Handle("/api/v1/approvals/deny", Protect(s, "", allowedOrigins))
+}
+
 func (s *Server) setupRoutes() {
 	// Health and info endpoints
 	s.mux.HandleFunc("/health", s.handleHealth)
 	s.mux.HandleFunc("/api/v1/health", s.handleHealth)
 	s.mux.HandleFunc("/api/v1/info", s.handleInfo)
 	
-	// MCP protocol endpoints (for LM Studio compatibility)
-	s.mux.HandleFunc("/mcp", s.handleMCPRequest)
-	s.mux.HandleFunc("/", s.corsMiddleware(s.handleMCPRequest))
-	
 	// MCP-inspired REST endpoints
 	s.mux.HandleFunc("/api/v1/tools", s.handleToolsList)
 	
 	// Email endpoints
 	s.mux.HandleFunc("/api/v1/email/send", s.handleSendEmail)
 	s.mux.HandleFunc("/api/v1/email/read", s.handleReadEmails)
-}
 
-func (s *Server) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		next(w, r)
-	}
+	// Outbox endpoints
+	s.mux.HandleFunc("/api/v1/outbox", s.handleListOutbox)
+	s.mux.HandleFunc("/api/v1/outbox/cancel", s.handleCancelScheduled)
+
+	// Approval endpoints
+	s.mux.HandleFunc("/api/v1/approvals", s.handleListApprovals)
+	s.mux.HandleFunc("/api/v1/approvals/approve", s.handleDecideApproval(true))
+	s.mux.HandleFunc("/api/v1/approvals/deny", s.handleDecideApproval(false))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -151,14 +114,18 @@ func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 	info := map[string]interface{}{
 		"name":        "AI Presence MCP Server",
 		"version":     "0.1.0",
-		"description": "HTTP API server providing MCP-like functionality for email and communication platforms",
+		"description": "REST API served next to the MCP endpoint for email and communication platforms",
 		"endpoints": map[string]string{
 			"health":     "/health",
+			"mcp":        "/mcp",
 			"tools":      "/api/v1/tools",
 			"sendEmail":  "/api/v1/email/send",
 			"readEmails": "/api/v1/email/read",
+			"outbox":     "/api/v1/outbox",
+			"approvals":  "/api/v1/approvals",
 		},
-		"emailAccounts": len(s.config.Email),
+		"emailAccounts": len(s.emailService.Accounts()),
+		"quotas":        s.emailService.Quotas(),
 	}
 	
 	s.writeJSONResponse(w, http.StatusOK, APIResponse{
@@ -175,9 +142,9 @@ func (s *Server) handleToolsList(w http.ResponseWriter, r *http.Request) {
 	
 	tools := []ToolInfo{}
 	
-	// Add email tools if email service is available
-	if s.emailService != nil {
-		sendEmailTool := email.NewSendEmailTool(s.emailService)
+	// Add email tools if any account is configured
+	if len(s.emailService.Accounts()) > 0 {
+		sendEmailTool := email.NewSendEmailTool(s.emailService, s.outbox)
 		readEmailsTool := email.NewReadEmailsTool(s.emailService)
 		
 		tools = append(tools, ToolInfo{
@@ -205,7 +172,7 @@ func (s *Server) handleSendEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	if s.emailService == nil {
+	if len(s.emailService.Accounts()) == 0 {
 		s.writeJSONError(w, http.StatusServiceUnavailable, "Email service not configured")
 		return
 	}
@@ -232,10 +199,39 @@ func (s *Server) handleSendEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// Dry runs return the rendered message without sending it
+	if req.DryRun || s.emailService.DryRun() {
+		rendered, err := s.emailService.PreviewEmail(req.To, req.Subject, req.Body, req.Account, req.Identity)
+		if err != nil {
+			s.writeJSONError(w, sendErrorStatus(err, http.StatusBadRequest), fmt.Sprintf("Invalid email: %v", err))
+			return
+		}
+		s.writeJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"dry_run": true,
+				"email":   rendered,
+			},
+		})
+		return
+	}
+
+	// Sends are held for approval exactly as send_email calls over MCP are
+	if s.approvals.Requires("send_email") {
+		s.submitSendForApproval(w, req)
+		return
+	}
+
+	// Scheduled emails go through the outbox
+	if req.SendAt != "" {
+		s.scheduleEmail(w, req)
+		return
+	}
+
 	// Send email
-	if err := s.emailService.SendEmail(req.To, req.Subject, req.Body, req.Account); err != nil {
+	if err := s.emailService.SendEmail(r.Context(), req.To, req.Subject, req.Body, req.Account, req.Identity); err != nil {
 		log.Printf("Failed to send email: %v", err)
-		s.writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to send email: %v", err))
+		s.writeJSONError(w, sendErrorStatus(err, http.StatusInternalServerError), fmt.Sprintf("Failed to send email: %v", err))
 		return
 	}
 	
@@ -247,13 +243,133 @@ func (s *Server) handleSendEmail(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// submitSendForApproval stores a send as a pending action, which is
+// delivered by the approval worker once a human approves it
+func (s *Server) submitSendForApproval(w http.ResponseWriter, req types.SendEmailRequest) {
+	args := map[string]interface{}{
+		"to":      req.To,
+		"subject": req.Subject,
+		"body":    req.Body,
+	}
+	for name, value := range map[string]string{"account": req.Account, "identity": req.Identity, "send_at": req.SendAt} {
+		if value != "" {
+			args[name] = value
+		}
+	}
+
+	tool := email.NewSendEmailTool(s.emailService, s.outbox)
+	preview, err := approval.Describe(tool.Name(), tool, args)
+	if err != nil {
+		s.writeJSONError(w, sendErrorStatus(err, http.StatusBadRequest), fmt.Sprintf("Invalid email: %v", err))
+		return
+	}
+
+	action, err := s.approvals.Submit(tool.Name(), args, preview)
+	if err != nil {
+		s.writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"approval_required": true,
+			"token":             action.Token,
+			"expires_at":        action.ExpiresAt,
+			"preview":           action.Preview,
+		},
+	})
+}
+
+// sendErrorStatus maps policy violations to 403 Forbidden, rate limits to
+// 429 Too Many Requests and any other error to fallback
+func sendErrorStatus(err error, fallback int) int {
+	var policyErr *email.PolicyViolationError
+	if errors.As(err, &policyErr) {
+		return http.StatusForbidden
+	}
+	var rateErr *email.RateLimitError
+	if errors.As(err, &rateErr) {
+		return http.StatusTooManyRequests
+	}
+	return fallback
+}
+
+func (s *Server) scheduleEmail(w http.ResponseWriter, req types.SendEmailRequest) {
+	sendAt, err := time.Parse(time.RFC3339, req.SendAt)
+	if err != nil {
+		s.writeJSONError(w, http.StatusBadRequest, "Invalid send_at: must be an RFC 3339 timestamp")
+		return
+	}
+
+	entry, err := s.outbox.Enqueue(req.To, req.Subject, req.Body, req.Account, req.Identity, sendAt)
+	if err != nil {
+		log.Printf("Failed to queue email: %v", err)
+		s.writeJSONError(w, sendErrorStatus(err, http.StatusInternalServerError), fmt.Sprintf("Failed to queue email: %v", err))
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Data:    entry,
+	})
+}
+
+func (s *Server) handleListOutbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	entries := s.outbox.List(r.URL.Query().Get("status"))
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"entries": entries,
+			"count":   len(entries),
+		},
+	})
+}
+
+func (s *Server) handleCancelScheduled(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if req.ID == "" {
+		s.writeJSONError(w, http.StatusBadRequest, "Missing required field: id")
+		return
+	}
+
+	entry, err := s.outbox.Cancel(req.ID)
+	if err != nil {
+		s.writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    entry,
+	})
+}
+
 func (s *Server) handleReadEmails(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 	
-	if s.emailService == nil {
+	if len(s.emailService.Accounts()) == 0 {
 		s.writeJSONError(w, http.StatusServiceUnavailable, "Email service not configured")
 		return
 	}
@@ -277,10 +393,10 @@ func (s *Server) handleReadEmails(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Read emails
-	emails, err := s.emailService.ReadEmails(account, folder, limit, unreadOnly)
+	emails, err := s.emailService.ReadEmails(r.Context(), account, folder, limit, unreadOnly)
 	if err != nil {
 		log.Printf("Failed to read emails: %v", err)
-		s.writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read emails: %v", err))
+		s.writeJSONError(w, sendErrorStatus(err, http.StatusInternalServerError), fmt.Sprintf("Failed to read emails: %v", err))
 		return
 	}
 	
@@ -293,158 +409,83 @@ func (s *Server) handleReadEmails(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) handleMCPRequest(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		s.writeMCPError(w, nil, -32601, "Method not allowed - MCP requires POST", nil)
+func (s *Server) handleListApprovals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	
-	var req MCPRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeMCPError(w, nil, -32700, "Parse error", nil)
-		return
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = approval.StatusPending
+	} else if status == "all" {
+		status = ""
 	}
-	
-	switch req.Method {
-	case "initialize":
-		s.handleMCPInitialize(w, req.ID, req.Params)
-	case "tools/list":
-		s.handleMCPToolsList(w, req.ID)
-	case "tools/call":
-		s.handleMCPToolCall(w, req.ID, req.Params)
-	default:
-		s.writeMCPError(w, req.ID, -32601, "Method not found", nil)
+
+	actions, err := s.approvals.List(status)
+	if err != nil {
+		s.writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
-}
 
-func (s *Server) handleMCPInitialize(w http.ResponseWriter, id interface{}, params interface{}) {
-	result := InitializeResult{
-		ProtocolVersion: "2024-11-05",
-		Capabilities: ServerCapabilities{
-			Tools: &ToolsCapability{},
-		},
-		ServerInfo: ServerInfo{
-			Name:    "AI Presence MCP Server",
-			Version: "0.1.0",
+	s.writeJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"actions": actions,
+			"count":   len(actions),
 		},
-	}
-	
-	s.writeMCPResponse(w, id, result)
+	})
 }
 
-func (s *Server) handleMCPToolsList(w http.ResponseWriter, id interface{}) {
-	var toolList []ToolInfo
-	
-	if s.emailService != nil {
-		sendEmailTool := email.NewSendEmailTool(s.emailService)
-		readEmailsTool := email.NewReadEmailsTool(s.emailService)
-		
-		toolList = append(toolList, ToolInfo{
-			Name:        sendEmailTool.Name(),
-			Description: sendEmailTool.Description(),
-			InputSchema: sendEmailTool.InputSchema(),
-		})
-		
-		toolList = append(toolList, ToolInfo{
-			Name:        readEmailsTool.Name(),
-			Description: readEmailsTool.Description(),
-			InputSchema: readEmailsTool.InputSchema(),
-		})
-	}
-	
-	result := map[string]interface{}{
-		"tools": toolList,
-	}
-	
-	s.writeMCPResponse(w, id, result)
-}
+// handleDecideApproval approves or denies a pending action. It requires
+// approval.http_token, which MCP clients are never given, so a client cannot
+// approve its own calls.
+func (s *Server) handleDecideApproval(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			s.writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
 
-func (s *Server) handleMCPToolCall(w http.ResponseWriter, id interface{}, params interface{}) {
-	if s.emailService == nil {
-		s.writeMCPError(w, id, -32603, "Email service not available", nil)
-		return
-	}
-	
-	var callParams ToolCallParams
-	paramBytes, err := json.Marshal(params)
-	if err != nil {
-		s.writeMCPError(w, id, -32602, "Invalid params", nil)
-		return
-	}
-	
-	if err := json.Unmarshal(paramBytes, &callParams); err != nil {
-		s.writeMCPError(w, id, -32602, "Invalid params", nil)
-		return
-	}
-	
-	var tool interface {
-		Name() string
-		Description() string
-		InputSchema() interface{}
-		Execute(args map[string]interface{}) (*types.ToolResult, error)
-	}
-	
-	switch callParams.Name {
-	case "send_email":
-		tool = email.NewSendEmailTool(s.emailService)
-	case "read_emails":
-		tool = email.NewReadEmailsTool(s.emailService)
-	default:
-		s.writeMCPError(w, id, -32601, fmt.Sprintf("Tool not found: %s", callParams.Name), nil)
-		return
-	}
-	
-	result, err := tool.Execute(callParams.Arguments)
-	if err != nil {
-		log.Printf("Tool execution error: %v", err)
-		isError := true
-		result = &types.ToolResult{
-			Content: []types.ToolContent{{
-				Type: "text",
-				Text: fmt.Sprintf("Error executing tool: %v", err),
-			}},
-			IsError: &isError,
+		if s.approvalToken == "" {
+			s.writeJSONError(w, http.StatusForbidden, "Approving over HTTP is disabled: set approval.http_token")
+			return
+		}
+		if !hasBearerToken(r, s.approvalToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeJSONError(w, http.StatusUnauthorized, "Missing or invalid approval token")
+			return
 		}
-	}
-	
-	s.writeMCPResponse(w, id, result)
-}
 
-func (s *Server) writeMCPResponse(w http.ResponseWriter, id interface{}, result interface{}) {
-	response := MCPResponse{
-		JSONRPC: "2.0",
-		ID:      id,
-		Result:  result,
-	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
-}
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
 
-func (s *Server) writeMCPError(w http.ResponseWriter, id interface{}, code int, message string, data interface{}) {
-	response := MCPResponse{
-		JSONRPC: "2.0",
-		ID:      id,
-		Error: &MCPError{
-			Code:    code,
-			Message: message,
-			Data:    data,
-		},
-	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
-}
+		if req.Token == "" {
+			s.writeJSONError(w, http.StatusBadRequest, "Missing required field: token")
+			return
+		}
 
-func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
-	// Check if this might be an MCP request to root
-	if r.Method == http.MethodPost {
-		s.handleMCPRequest(w, r)
-		return
+		decide := s.approvals.Deny
+		if approve {
+			decide = s.approvals.Approve
+		}
+
+		action, err := decide(req.Token, "http:"+r.RemoteAddr)
+		if err != nil {
+			s.writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		s.writeJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    action,
+		})
 	}
-	s.writeJSONError(w, http.StatusNotFound, "Endpoint not found")
 }
 
 func (s *Server) writeJSONResponse(w http.ResponseWriter, statusCode int, response APIResponse) {
@@ -459,16 +500,3 @@ func (s *Server) writeJSONError(w http.ResponseWriter, statusCode int, errorMess
 		Error:   errorMessage,
 	})
 }
-
-func (s *Server) Start(port int) error {
-	addr := fmt.Sprintf(":%d", port)
-	log.Printf("Starting HTTP API server on %s", addr)
-	log.Printf("Available endpoints:")
-	log.Printf("  GET  /health - Health check")
-	log.Printf("  GET  /api/v1/info - Server information")
-	log.Printf("  GET  /api/v1/tools - List available tools")
-	log.Printf("  POST /api/v1/email/send - Send email")
-	log.Printf("  GET  /api/v1/email/read - Read emails")
-	
-	return http.ListenAndServe(addr, s.mux)
-}
\ No newline at end of file
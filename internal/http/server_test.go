@@ -0,0 +1,187 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/internal/approval"
+	"ai-presence-mcp/internal/email"
+	"ai-presence-mcp/internal/ratelimit"
+	"ai-presence-mcp/pkg/types"
+)
+
+const (
+	testToken         = "client-token"
+	testApprovalToken = "approval-token"
+)
+
+// newTestMux mounts an API holding every send for approval, as serveHTTP does
+func newTestMux(t *testing.T) (*http.ServeMux, *Server) {
+	t.Helper()
+	dir := t.TempDir()
+
+	service := email.NewService([]types.EmailConfig{{
+		Username:   "agent@example.com",
+		Password:   "secret",
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   1,
+		Limits:     types.AccountLimits{DailySendQuota: 5},
+	}})
+	limits, err := ratelimit.Open(filepath.Join(dir, "ratelimits.json"))
+	if err != nil {
+		t.Fatalf("ratelimit.Open: %v", err)
+	}
+	service.SetLimiter(email.NewLimiter(limits))
+	outbox, err := email.NewOutbox(service, types.OutboxConfig{
+		Path:           filepath.Join(dir, "outbox.json"),
+		MaxAttempts:    1,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  time.Hour,
+		PollInterval:   time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewOutbox: %v", err)
+	}
+	approvals := approval.NewManager(types.ApprovalConfig{
+		Mode:         approval.ModeRequired,
+		Tools:        []string{"send_email"},
+		TTL:          time.Minute,
+		Path:         filepath.Join(dir, "approvals.json"),
+		PollInterval: time.Second,
+	})
+
+	api := NewServer(service, outbox, approvals, testApprovalToken)
+	mux := http.NewServeMux()
+	api.Mount(mux, testToken, []string{"https://app.example.com"})
+	return mux, api
+}
+
+func serve(mux *http.ServeMux, method, path, token, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProtect(t *testing.T) {
+	mux, _ := newTestMux(t)
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		header http.Header
+		want   int
+	}{
+		{"no token", http.MethodGet, "", nil, http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "guess", nil, http.StatusUnauthorized},
+		{"token", http.MethodGet, testToken, nil, http.StatusOK},
+		{"other origin", http.MethodGet, testToken, http.Header{"Origin": {"https://evil.example"}}, http.StatusForbidden},
+		{"allowed origin", http.MethodGet, testToken, http.Header{"Origin": {"https://app.example.com"}}, http.StatusOK},
+		{"preflight", http.MethodOptions, "", http.Header{
+			"Origin":                        {"https://app.example.com"},
+			"Access-Control-Request-Method": {"POST"},
+		}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, tt.method, "/api/v1/info", tt.token, "", tt.header)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if origin := tt.header.Get("Origin"); rec.Code < 400 && origin != "" {
+				if got := rec.Header().Get("Access-Control-Allow-Origin"); got != origin {
+					t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, origin)
+				}
+			}
+		})
+	}
+}
+
+func TestSendNeedsApprovalToken(t *testing.T) {
+	mux, api := newTestMux(t)
+
+	rec := serve(mux, http.MethodPost, "/api/v1/email/send", testToken,
+		`{"to": "someone@example.com", "subject": "Hi", "body": "Hello"}`, nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("send status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Data.Token == "" {
+		t.Fatalf("expected an approval token, got %s", rec.Body)
+	}
+	decision := `{"token": "` + resp.Data.Token + `"}`
+
+	// The token MCP clients hold must not approve their own sends
+	if rec := serve(mux, http.MethodPost, "/api/v1/approvals/approve", testToken, decision, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("approve with client token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := serve(mux, http.MethodPost, "/api/v1/approvals/approve", testApprovalToken, decision, nil); rec.Code != http.StatusOK {
+		t.Fatalf("approve: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	actions, err := api.approvals.List(approval.StatusApproved)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(actions) != 1 || actions[0].Tool != "send_email" {
+		t.Fatalf("expected the send to be approved, got %+v", actions)
+	}
+}
+
+func TestOutboxEndpoints(t *testing.T) {
+	mux, api := newTestMux(t)
+
+	entry, err := api.outbox.Enqueue("someone@example.com", "Later", "Hello", "", "", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	rec := serve(mux, http.MethodGet, "/api/v1/outbox", testToken, "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), entry.ID) {
+		t.Fatalf("list: status = %d, expected %s in %s", rec.Code, entry.ID, rec.Body)
+	}
+
+	rec = serve(mux, http.MethodPost, "/api/v1/outbox/cancel", testToken, `{"id": "`+entry.ID+`"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if pending := api.outbox.List(email.OutboxPending); len(pending) != 0 {
+		t.Errorf("expected no pending entries after cancelling, got %d", len(pending))
+	}
+}
+
+func TestInfoReportsQuotas(t *testing.T) {
+	mux, _ := newTestMux(t)
+
+	rec := serve(mux, http.MethodGet, "/api/v1/info", testToken, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			Quotas []email.QuotaStatus `json:"quotas"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(resp.Data.Quotas) != 1 || resp.Data.Quotas[0].DailyQuota != 5 {
+		t.Fatalf("expected the account's daily quota of 5, got %+v", resp.Data.Quotas)
+	}
+}
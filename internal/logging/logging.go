@@ -0,0 +1,250 @@
+// Package logging sets up the server's structured logs. Entries are written
+// to stderr or a file, never stdout, which carries JSON-RPC over stdio, and
+// may also be forwarded to MCP clients. Credentials and message content are
+// redacted wherever entries go.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Redacted replaces values that must not be logged
+const Redacted = "[REDACTED]"
+
+// warningPrefix starts the log package messages logged at warn level
+const warningPrefix = "Warning: "
+
+// minSecretLength is the shortest configured secret scrubbed from log text;
+// shorter ones would mangle unrelated text
+const minSecretLength = 4
+
+// sensitiveKeys name attributes and arguments whose values are never logged:
+// credentials and message content
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"passphrase":    true,
+	"secret":        true,
+	"client_secret": true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"body":          true,
+	"html_body":     true,
+	"content":       true,
+}
+
+var (
+	level   = new(slog.LevelVar)
+	secrets atomic.Pointer[[]string]
+	forward atomic.Pointer[slog.Handler]
+)
+
+// ParseLevel returns the level named by a log_level value: debug, info, warn
+// or error
+func ParseLevel(name string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return l, nil
+}
+
+// Setup makes the default slog logger, and with it the log package, write
+// entries at levelName and above to file, or to stderr if file is empty. The
+// returned function closes the file.
+func Setup(levelName, file string) (func() error, error) {
+	if err := SetLevel(levelName); err != nil {
+		return nil, err
+	}
+
+	var out io.Writer = os.Stderr
+	closeFile := func() error { return nil }
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		out, closeFile = f, f.Close
+	}
+
+	slog.SetDefault(slog.New(newHandler(slog.NewTextHandler(out, &slog.HandlerOptions{Level: level}))))
+	return closeFile, nil
+}
+
+// SetLevel changes the level entries are written at
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// SetSecrets replaces the configured secrets scrubbed from log text, such as
+// account passwords quoted back in a server's error
+func SetSecrets(values []string) {
+	var kept []string
+	for _, v := range values {
+		if len(v) >= minSecretLength {
+			kept = append(kept, v)
+		}
+	}
+	secrets.Store(&kept)
+}
+
+// Forward sends redacted entries to h as well, whatever its level; nil stops
+// forwarding
+func Forward(h slog.Handler) {
+	if h == nil {
+		forward.Store(nil)
+		return
+	}
+	forward.Store(&h)
+}
+
+// handler redacts entries, then writes them to out and hands them to the
+// forwarding handler
+type handler struct {
+	out slog.Handler
+	// ops are the WithAttrs and WithGroup calls made on this handler,
+	// replayed on the forwarding handler since it may be set later
+	ops []func(slog.Handler) slog.Handler
+}
+
+func newHandler(out slog.Handler) *handler {
+	return &handler{out: out}
+}
+
+func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
+	// The log package logs everything at info, and Handle raises warnings
+	// from it to warn, so info always gets that far
+	if l == slog.LevelInfo || h.out.Enabled(ctx, l) {
+		return true
+	}
+	if f := forward.Load(); f != nil {
+		return (*f).Enabled(ctx, l)
+	}
+	return false
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level == slog.LevelInfo && strings.HasPrefix(r.Message, warningPrefix) {
+		r.Level = slog.LevelWarn
+	}
+
+	redacted := slog.NewRecord(r.Time, r.Level, scrub(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+
+	var err error
+	if h.out.Enabled(ctx, redacted.Level) {
+		err = h.out.Handle(ctx, redacted)
+	}
+	if f := forward.Load(); f != nil {
+		fh := *f
+		for _, op := range h.ops {
+			fh = op(fh)
+		}
+		if fh.Enabled(ctx, redacted.Level) {
+			// Forwarding failures are not logged, which could loop
+			_ = fh.Handle(ctx, redacted.Clone())
+		}
+	}
+	return err
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(redacted) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{out: op(h.out), ops: append(ops, op)}
+}
+
+// redactAttr hides the value of a sensitive attribute, descending into
+// groups and argument maps, and scrubs secrets from text
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(scrub(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		a.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case map[string]interface{}:
+			a.Value = slog.AnyValue(redactArgs(v))
+		case map[string]string:
+			// Prompt arguments
+			args := make(map[string]interface{}, len(v))
+			for k, s := range v {
+				args[k] = s
+			}
+			a.Value = slog.AnyValue(redactArgs(args))
+		case error:
+			a.Value = slog.StringValue(scrub(v.Error()))
+		}
+	}
+	return a
+}
+
+// redactArgs returns a copy of tool or prompt arguments that is safe to log
+func redactArgs(args map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if sensitiveKeys[strings.ToLower(k)] {
+			redacted[k] = Redacted
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			redacted[k] = scrub(v)
+		case map[string]interface{}:
+			redacted[k] = redactArgs(v)
+		default:
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+// scrub replaces the configured secrets in s
+func scrub(s string) string {
+	values := secrets.Load()
+	if values == nil {
+		return s
+	}
+	for _, v := range *values {
+		s = strings.ReplaceAll(s, v, Redacted)
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func newTestLogger(t *testing.T, levelName string) (*slog.Logger, *bytes.Buffer) {
+	t.Helper()
+	if err := SetLevel(levelName); err != nil {
+		t.Fatalf("SetLevel: %v", err)
+	}
+	t.Cleanup(func() {
+		SetLevel("info")
+		SetSecrets(nil)
+		Forward(nil)
+	})
+	var buf bytes.Buffer
+	return slog.New(newHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))), &buf
+}
+
+func TestRedaction(t *testing.T) {
+	logger, buf := newTestLogger(t, "info")
+	SetSecrets([]string{"hunter22", "x"})
+
+	logger.Info("Tool called", "tool", "send_email", "args", map[string]interface{}{
+		"to":      "bob@example.com",
+		"body":    "the quarterly numbers",
+		"options": map[string]interface{}{"password": "pw"},
+	})
+	logger.Debug("Prompt arguments", "args", map[string]string{"content": "the draft"})
+	logger.Info("Login failed", "error", "535 bad password hunter22", "Token", "abc")
+	logger.With("secret", "s3cret").Info("Connected")
+
+	out := buf.String()
+	for _, leaked := range []string{"quarterly", "pw]", "hunter22", "abc", "s3cret", "the draft"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("log leaks %q:\n%s", leaked, out)
+		}
+	}
+	// "x" is too short to scrub safely, so the address survives
+	for _, kept := range []string{"bob@example.com", "535 bad password [REDACTED]", "tool=send_email"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("log lacks %q:\n%s", kept, out)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	logger, buf := newTestLogger(t, "warn")
+
+	logger.Info("routine")
+	logger.Info("Warning: disk almost full")
+	logger.Error("broken")
+	out := buf.String()
+	if strings.Contains(out, "routine") {
+		t.Errorf("info entry written at warn level:\n%s", out)
+	}
+	if !strings.Contains(out, "level=WARN msg=\"Warning: disk almost full\"") {
+		t.Errorf("expected the warning to be raised to warn level:\n%s", out)
+	}
+	if !strings.Contains(out, "broken") {
+		t.Errorf("error entry missing:\n%s", out)
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestForward(t *testing.T) {
+	logger, buf := newTestLogger(t, "error")
+	var forwarded bytes.Buffer
+	Forward(slog.NewTextHandler(&forwarded, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	logger.With("account", "work").Debug("Fetching", "password", "pw")
+	if buf.Len() != 0 {
+		t.Errorf("debug entry written at error level:\n%s", buf.String())
+	}
+	out := forwarded.String()
+	if !strings.Contains(out, "account=work") || !strings.Contains(out, "password=[REDACTED]") {
+		t.Errorf("expected the redacted entry to be forwarded, got:\n%s", out)
+	}
+
+	Forward(nil)
+	logger.Error("after")
+	if strings.Contains(forwarded.String(), "after") {
+		t.Error("entry forwarded after forwarding stopped")
+	}
+}
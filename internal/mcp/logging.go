@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// loggerName identifies the server's entries in notifications/message
+const loggerName = "ai-presence-mcp"
+
+// logQueueSize is how many entries wait to be forwarded before new ones are
+// dropped
+const logQueueSize = 256
+
+// logSendTimeout bounds sending one entry to one client
+const logSendTimeout = 5 * time.Second
+
+// logSessionKey carries the session whose request is being handled
+type logSessionKey struct{}
+
+// withLogSession marks entries logged under ctx as produced by a request of
+// session, so they are forwarded to it
+func withLogSession(ctx context.Context, session *sdkmcp.ServerSession) context.Context {
+	return context.WithValue(ctx, logSessionKey{}, session)
+}
+
+// logEntry is an entry waiting to be forwarded to the session it belongs to
+type logEntry struct {
+	session *sdkmcp.ServerSession
+	params  *sdkmcp.LoggingMessageParams
+}
+
+// LogHandler returns a handler forwarding log entries to clients as
+// notifications/message. A client receives only the entries logged while
+// handling its own requests, at or above the level it chose with
+// logging/setLevel, and none until it chooses one; entries of the server as
+// a whole are never forwarded. Entries are sent in the background until ctx
+// ends, so logging never waits for a client; those that arrive while
+// clients lag behind are dropped.
+func (s *Server) LogHandler(ctx context.Context) slog.Handler {
+	queue := make(chan logEntry, logQueueSize)
+	go s.forwardLogs(ctx, queue)
+
+	buf := new(bytes.Buffer)
+	return &logHandler{
+		queue: queue,
+		mu:    new(sync.Mutex),
+		buf:   buf,
+		json: slog.NewJSONHandler(buf, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			// The level is part of the notification
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.LevelKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+	}
+}
+
+func (s *Server) forwardLogs(ctx context.Context, queue <-chan logEntry) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-queue:
+			sendCtx, cancel := context.WithTimeout(ctx, logSendTimeout)
+			// Failures are not logged, which would forward them again
+			_ = entry.session.Log(sendCtx, entry.params)
+			cancel()
+		}
+	}
+}
+
+// logHandler encodes the entries of sessions' requests as JSON and queues
+// them for forwarding
+type logHandler struct {
+	queue chan<- logEntry
+	// mu guards buf, which json writes each entry to; both are shared with
+	// the handlers derived by WithAttrs and WithGroup
+	mu   *sync.Mutex
+	buf  *bytes.Buffer
+	json slog.Handler
+}
+
+// Enabled reports true for every level; sessions filter entries by their own
+func (h *logHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	session, _ := ctx.Value(logSessionKey{}).(*sdkmcp.ServerSession)
+	if session == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	h.buf.Reset()
+	err := h.json.Handle(ctx, r)
+	data := json.RawMessage(bytes.Clone(h.buf.Bytes()))
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case h.queue <- logEntry{session, &sdkmcp.LoggingMessageParams{Logger: loggerName, Level: loggingLevel(r.Level), Data: data}}:
+	default:
+	}
+	return nil
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.json = h.json.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.json = h.json.WithGroup(name)
+	return &h2
+}
+
+// loggingLevel maps a slog level to the nearest MCP logging level
+func loggingLevel(l slog.Level) sdkmcp.LoggingLevel {
+	switch {
+	case l >= slog.LevelError:
+		return "error"
+	case l >= slog.LevelWarn:
+		return "warning"
+	case l >= slog.LevelInfo:
+		return "info"
+	}
+	return "debug"
+}
+
+// argNames returns the sorted names of the arguments of a call, which are
+// logged at info in place of their values
+func argNames[V any](args map[string]V) []string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
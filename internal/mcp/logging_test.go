@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// noisyTool logs while it runs
+type noisyTool struct{ logger *slog.Logger }
+
+func (t *noisyTool) Name() string             { return "noisy" }
+func (t *noisyTool) Description() string      { return "logs" }
+func (t *noisyTool) InputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (t *noisyTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	t.logger.InfoContext(ctx, "routine")
+	t.logger.WarnContext(ctx, "careful", "uri", "email://work/INBOX")
+	return &types.ToolResult{Content: []types.ToolContent{{Type: "text", Text: "done"}}}, nil
+}
+
+func TestLogHandlerForwardsToClients(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	logger := slog.New(server.LogHandler(ctx))
+	server.RegisterTool(&noisyTool{logger})
+
+	listen := func() (*sdkmcp.ClientSession, chan *sdkmcp.LoggingMessageParams) {
+		messages := make(chan *sdkmcp.LoggingMessageParams, 10)
+		session := connect(t, server, &sdkmcp.ClientOptions{
+			LoggingMessageHandler: func(_ context.Context, req *sdkmcp.LoggingMessageRequest) {
+				messages <- req.Params
+			},
+		})
+		// Only entries at or above the client's level are sent
+		if err := session.SetLoggingLevel(ctx, &sdkmcp.SetLoggingLevelParams{Level: "warning"}); err != nil {
+			t.Fatalf("SetLoggingLevel: %v", err)
+		}
+		return session, messages
+	}
+	first, firstMessages := listen()
+	_, otherMessages := listen()
+
+	// Entries of the server as a whole and of other sessions' requests
+	// stay private
+	logger.Warn("outbox delivered", "to", "friend@example.com")
+	if _, err := first.CallTool(ctx, &sdkmcp.CallToolParams{Name: "noisy"}); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+
+	select {
+	case msg := <-firstMessages:
+		data, _ := msg.Data.(map[string]interface{})
+		if msg.Level != "warning" || msg.Logger != loggerName || data["msg"] != "careful" || data["uri"] != "email://work/INBOX" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("expected a log message")
+	}
+
+	select {
+	case msg := <-firstMessages:
+		t.Errorf("unexpected second message %+v", msg)
+	case msg := <-otherMessages:
+		t.Errorf("expected the other client to receive nothing, got %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/internal/progress"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressInterval is the least time between two progress notifications
+// for one request; the update completing the work is always sent
+const progressInterval = 250 * time.Millisecond
+
+// withProgress forwards progress reported under ctx to the client as
+// notifications/progress if the request carried a progress token. Updates
+// that do not advance the progress are dropped, as the protocol requires.
+// Over streamable HTTP the notifications are sent on the request's own
+// event stream.
+func withProgress(ctx context.Context, session *sdkmcp.ServerSession, token any) context.Context {
+	if token == nil || session == nil {
+		return ctx
+	}
+
+	var (
+		mu      sync.Mutex
+		last    time.Time
+		sent    float64
+		started bool
+	)
+	return progress.WithReporter(ctx, func(done, total float64, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		complete := total > 0 && done >= total
+		if (started && done <= sent) || (!complete && time.Since(last) < progressInterval) {
+			return
+		}
+		last, sent, started = time.Now(), done, true
+
+		err := session.NotifyProgress(ctx, &sdkmcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      done,
+			Total:         total,
+			Message:       message,
+		})
+		if err != nil {
+			slog.Warn("Failed to send progress notification", "error", err)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxCompletions is the most values a completion/complete response may hold
+const maxCompletions = 100
+
+// Prompt is a message template clients offer their users, rendered with
+// the arguments the user fills in
+type Prompt interface {
+	Name() string
+	Description() string
+	Arguments() []types.PromptArgument
+	Get(ctx context.Context, args map[string]string) (*types.PromptResult, error)
+}
+
+// Completer is implemented by prompts and resource templates that suggest
+// values for their arguments. given holds the arguments already given.
+type Completer interface {
+	Complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error)
+}
+
+// SetPrompts makes prompts the complete set of registered prompts, like
+// SetTools does for tools
+func (s *Server) SetPrompts(prompts []Prompt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[string]bool, len(prompts))
+	for _, prompt := range prompts {
+		wanted[prompt.Name()] = true
+		if s.prompts[prompt.Name()] != prompt {
+			s.registerPromptLocked(prompt)
+		}
+	}
+
+	var removed []string
+	for name := range s.prompts {
+		if !wanted[name] {
+			removed = append(removed, name)
+			delete(s.prompts, name)
+		}
+	}
+	if len(removed) > 0 {
+		slog.Info("Removing prompts", "prompts", removed)
+		s.mcpServer.RemovePrompts(removed...)
+	}
+}
+
+// registerPromptLocked adds prompt to the MCP server. s.mu must be held.
+func (s *Server) registerPromptLocked(prompt Prompt) {
+	slog.Debug("Registering prompt", "prompt", prompt.Name())
+	s.prompts[prompt.Name()] = prompt
+
+	def := &sdkmcp.Prompt{
+		Name:        prompt.Name(),
+		Description: prompt.Description(),
+	}
+	for _, arg := range prompt.Arguments() {
+		def.Arguments = append(def.Arguments, &sdkmcp.PromptArgument{
+			Name:        arg.Name,
+			Description: arg.Description,
+			Required:    arg.Required,
+		})
+	}
+
+	s.mcpServer.AddPrompt(def, func(ctx context.Context, req *sdkmcp.GetPromptRequest) (*sdkmcp.GetPromptResult, error) {
+		args := req.Params.Arguments
+		if args == nil {
+			args = make(map[string]string)
+		}
+		for _, arg := range prompt.Arguments() {
+			if arg.Required && args[arg.Name] == "" {
+				return nil, fmt.Errorf("prompt %s requires the argument %q", prompt.Name(), arg.Name)
+			}
+		}
+
+		ctx = withLogSession(ctx, req.Session)
+		slog.InfoContext(ctx, "Prompt requested", "prompt", prompt.Name(), "args", argNames(args))
+		slog.DebugContext(ctx, "Prompt arguments", "prompt", prompt.Name(), "args", args)
+		ctx = withProgress(ctx, req.Session, req.Params.GetProgressToken())
+		result, err := prompt.Get(ctx, args)
+		if err != nil {
+			slog.WarnContext(ctx, "Prompt failed", "prompt", prompt.Name(), "error", err)
+			return nil, err
+		}
+		return toGetPromptResult(result), nil
+	})
+}
+
+func toGetPromptResult(result *types.PromptResult) *sdkmcp.GetPromptResult {
+	res := &sdkmcp.GetPromptResult{Description: result.Description}
+	for _, m := range result.Messages {
+		var content sdkmcp.Content = &sdkmcp.TextContent{Text: m.Text}
+		if m.Resource != nil {
+			content = &sdkmcp.EmbeddedResource{Resource: &sdkmcp.ResourceContents{
+				URI:      m.Resource.URI,
+				MIMEType: m.Resource.MIMEType,
+				Text:     m.Resource.Text,
+				Blob:     m.Resource.Blob,
+			}}
+		}
+		res.Messages = append(res.Messages, &sdkmcp.PromptMessage{
+			Role:    sdkmcp.Role(m.Role),
+			Content: content,
+		})
+	}
+	return res
+}
+
+// complete answers completion/complete for prompt arguments and resource
+// template variables
+func (s *Server) complete(ctx context.Context, req *sdkmcp.CompleteRequest) (*sdkmcp.CompleteResult, error) {
+	ref := req.Params.Ref
+	if ref == nil {
+		return nil, fmt.Errorf("completion request has no ref")
+	}
+
+	var target interface{}
+	s.mu.Lock()
+	switch ref.Type {
+	case "ref/prompt":
+		if prompt, ok := s.prompts[ref.Name]; ok {
+			target = prompt
+		}
+	case "ref/resource":
+		if t, ok := s.resourceTemplates[ref.URI]; ok {
+			target = t
+		}
+	}
+	s.mu.Unlock()
+
+	result := &sdkmcp.CompleteResult{Completion: sdkmcp.CompletionResultDetails{Values: []string{}}}
+	completer, ok := target.(Completer)
+	if !ok {
+		return result, nil
+	}
+
+	var given map[string]string
+	if req.Params.Context != nil {
+		given = req.Params.Context.Arguments
+	}
+	values, err := completer.Complete(ctx, req.Params.Argument.Name, req.Params.Argument.Value, given)
+	if err != nil {
+		slog.WarnContext(withLogSession(ctx, req.Session), "Completion failed", "argument", req.Params.Argument.Name, "ref", ref.Name+ref.URI, "error", err)
+		return result, nil
+	}
+
+	result.Completion.Total = len(values)
+	if len(values) > maxCompletions {
+		values = values[:maxCompletions]
+		result.Completion.HasMore = true
+	}
+	result.Completion.Values = append(result.Completion.Values, values...)
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type greetPrompt struct{}
+
+func (greetPrompt) Name() string        { return "greet" }
+func (greetPrompt) Description() string { return "Greet someone" }
+func (greetPrompt) Arguments() []types.PromptArgument {
+	return []types.PromptArgument{{Name: "name", Required: true}, {Name: "tone"}}
+}
+func (greetPrompt) Get(ctx context.Context, args map[string]string) (*types.PromptResult, error) {
+	return &types.PromptResult{Messages: []types.PromptMessage{
+		{Role: "user", Text: "Greet " + args["name"]},
+		{Role: "user", Resource: &types.ResourceContent{URI: "note://" + args["name"], MIMEType: "text/plain", Text: "profile"}},
+	}}, nil
+}
+func (greetPrompt) Complete(ctx context.Context, argument, value string, given map[string]string) ([]string, error) {
+	if argument != "tone" {
+		return nil, nil
+	}
+	var values []string
+	for _, tone := range []string{"formal", "friendly", "funny"} {
+		if strings.HasPrefix(tone, value) {
+			values = append(values, tone)
+		}
+	}
+	return values, nil
+}
+
+func TestPrompts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	server.SetPrompts([]Prompt{greetPrompt{}})
+
+	session := connect(t, server, nil)
+
+	list, err := session.ListPrompts(ctx, nil)
+	if err != nil || len(list.Prompts) != 1 || len(list.Prompts[0].Arguments) != 2 || !list.Prompts[0].Arguments[0].Required {
+		t.Fatalf("expected the greet prompt with its arguments, got %+v, %v", list, err)
+	}
+
+	if _, err := session.GetPrompt(ctx, &sdkmcp.GetPromptParams{Name: "greet"}); err == nil ||
+		!strings.Contains(err.Error(), `requires the argument "name"`) {
+		t.Errorf("expected a missing required argument to be reported, got %v", err)
+	}
+
+	res, err := session.GetPrompt(ctx, &sdkmcp.GetPromptParams{Name: "greet", Arguments: map[string]string{"name": "Ada"}})
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if len(res.Messages) != 2 || res.Messages[0].Content.(*sdkmcp.TextContent).Text != "Greet Ada" {
+		t.Fatalf("unexpected messages %+v", res.Messages)
+	}
+	if embedded, ok := res.Messages[1].Content.(*sdkmcp.EmbeddedResource); !ok || embedded.Resource.URI != "note://Ada" {
+		t.Errorf("expected an embedded resource, got %+v", res.Messages[1].Content)
+	}
+
+	completion, err := session.Complete(ctx, &sdkmcp.CompleteParams{
+		Ref:      &sdkmcp.CompleteReference{Type: "ref/prompt", Name: "greet"},
+		Argument: sdkmcp.CompleteParamsArgument{Name: "tone", Value: "f"},
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got := completion.Completion.Values; len(got) != 3 || completion.Completion.Total != 3 {
+		t.Errorf("expected three tones, got %+v", completion.Completion)
+	}
+
+	server.SetPrompts(nil)
+	if list, err := session.ListPrompts(ctx, nil); err != nil || len(list.Prompts) != 0 {
+		t.Errorf("expected no prompts after removal, got %+v, %v", list, err)
+	}
+}
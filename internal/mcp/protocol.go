@@ -2,89 +2,323 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
+	"time"
 
+	"ai-presence-mcp/internal/approval"
 	"ai-presence-mcp/pkg/types"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 type Server struct {
 	mcpServer *sdkmcp.Server
+	approvals *approval.Manager
+
+	mu      sync.Mutex
+	tools   map[string]Tool
+	prompts map[string]Prompt
+	// toolTimeout bounds tool calls unless toolTimeouts names the tool
+	toolTimeout  time.Duration
+	toolTimeouts map[string]time.Duration
+
+	// templates are consulted in order to serve resources/read
+	templates         []registeredTemplate
+	resourceTemplates map[string]ResourceTemplate
+	resources         map[string]types.Resource
+	// subscriptions maps each subscribed URI to its sessions
+	subscriptions map[string]map[*sdkmcp.ServerSession]bool
 }
 
 type Tool interface {
 	Name() string
 	Description() string
 	InputSchema() interface{}
-	Execute(args map[string]interface{}) (*types.ToolResult, error)
+	// Execute runs the tool. ctx ends when the client cancels the call or
+	// disconnects, or when the tool's timeout passes.
+	Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error)
 }
 
 func NewServer() *Server {
-	log.Printf("Creating new MCP server...")
+	slog.Debug("Creating MCP server")
 
-	mcpServer := sdkmcp.NewServer(&sdkmcp.Implementation{
+	s := &Server{
+		tools:             make(map[string]Tool),
+		prompts:           make(map[string]Prompt),
+		resourceTemplates: make(map[string]ResourceTemplate),
+		resources:         make(map[string]types.Resource),
+		subscriptions:     make(map[string]map[*sdkmcp.ServerSession]bool),
+	}
+	s.mcpServer = sdkmcp.NewServer(&sdkmcp.Implementation{
 		Name:    "ai-presence-mcp",
 		Title:   "AI Presence MCP Server",
 		Version: "0.1.0",
 	}, &sdkmcp.ServerOptions{
 		Instructions: "AI Presence automation server providing email management and other productivity tools.",
+		// Tools, resources and prompts come and go as the configuration
+		// is reloaded, so always advertise them and their list_changed
+		// notifications
+		HasTools:           true,
+		HasResources:       true,
+		HasPrompts:         true,
+		CompletionHandler:  s.complete,
+		SubscribeHandler:   s.subscribe,
+		UnsubscribeHandler: s.unsubscribe,
 		InitializedHandler: func(ctx context.Context, req *sdkmcp.InitializedRequest) {
-			log.Printf("MCP client initialized!")
+			slog.Info("MCP client initialized", "client", clientName(req.Session))
 		},
 	})
 
-	log.Printf("MCP server created successfully")
-	return &Server{
-		mcpServer: mcpServer,
-	}
+	slog.Debug("MCP server created")
+	return s
+}
+
+// SetApprovals enables human-in-the-loop approval for the tools the manager
+// requires it for. It must be called before tools are registered.
+func (s *Server) SetApprovals(manager *approval.Manager) {
+	s.approvals = manager
 }
 
 func (s *Server) RegisterTool(tool Tool) {
-	log.Printf("Registering tool: %s", tool.Name())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registerLocked(tool)
+}
+
+// SetTools makes tools the complete set of registered tools, registering new
+// ones and removing those that are gone. Connected clients are sent
+// notifications/tools/list_changed; calls already running are unaffected.
+func (s *Server) SetTools(tools []Tool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		wanted[tool.Name()] = true
+		if s.tools[tool.Name()] != tool {
+			s.registerLocked(tool)
+		}
+	}
+
+	var removed []string
+	for name := range s.tools {
+		if !wanted[name] {
+			removed = append(removed, name)
+			delete(s.tools, name)
+		}
+	}
+	if len(removed) > 0 {
+		slog.Info("Removing tools", "tools", removed)
+		s.mcpServer.RemoveTools(removed...)
+	}
+}
+
+// registerLocked adds tool to the MCP server, replacing any tool with the
+// same name. s.mu must be held.
+func (s *Server) registerLocked(tool Tool) {
+	slog.Debug("Registering tool", "tool", tool.Name())
+	inputSchema, resolved, err := toolSchema(tool)
+	if err != nil {
+		slog.Error("Not registering tool", "tool", tool.Name(), "error", err)
+		return
+	}
+	s.tools[tool.Name()] = tool
+
+	gated := s.approvals != nil && s.approvals.Requires(tool.Name())
+	if gated {
+		s.approvals.RegisterExecutor(tool.Name(), func(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+			ctx, cancel, _ := s.toolContext(ctx, tool.Name())
+			defer cancel()
+			return tool.Execute(ctx, args)
+		})
+	}
 
 	toolDef := &sdkmcp.Tool{
 		Name:        tool.Name(),
 		Description: tool.Description(),
-		// InputSchema will be auto-generated by Go SDK
+		InputSchema: inputSchema,
 	}
 
-	// Create properly typed handler function
-	handler := func(ctx context.Context, req *sdkmcp.CallToolRequest, args map[string]interface{}) (*sdkmcp.CallToolResult, any, error) {
-		log.Printf("Tool '%s' called with args: %+v", tool.Name(), args)
-
-		result, err := tool.Execute(args)
+	handler := func(ctx context.Context, req *sdkmcp.CallToolRequest) (*sdkmcp.CallToolResult, error) {
+		ctx = withLogSession(ctx, req.Session)
+		args, err := decodeArguments(req.Params.Arguments, resolved)
 		if err != nil {
-			log.Printf("Tool '%s' error: %v", tool.Name(), err)
+			slog.WarnContext(ctx, "Tool called with invalid arguments", "tool", tool.Name(), "error", err)
 			return &sdkmcp.CallToolResult{
-				Content: []sdkmcp.Content{&sdkmcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+				Content: []sdkmcp.Content{&sdkmcp.TextContent{Text: fmt.Sprintf("Invalid arguments for %s: %v", tool.Name(), err)}},
 				IsError: true,
-			}, nil, nil
+			}, nil
 		}
+		slog.InfoContext(ctx, "Tool called", "tool", tool.Name(), "args", argNames(args))
+		slog.DebugContext(ctx, "Tool arguments", "tool", tool.Name(), "args", args)
+		ctx = withProgress(ctx, req.Session, req.Params.GetProgressToken())
+		ctx = withSampling(ctx, req.Session)
 
-		// Convert our internal result format to MCP format
-		var content []sdkmcp.Content
-		for _, c := range result.Content {
-			content = append(content, &sdkmcp.TextContent{Text: c.Text})
+		if gated && approval.NeedsApproval(tool, args) {
+			return s.executeWithApproval(ctx, req, tool, args)
 		}
+		return s.execute(ctx, tool, args)
+	}
+
+	// The raw handler receives the arguments undecoded; they are checked
+	// against the tool's own schema above, which is also what clients see
+	s.mcpServer.AddTool(toolDef, handler)
+}
+
+// execute runs a tool call within the tool's timeout. A call the client
+// cancelled gets an error in place of a result; one that timed out gets an
+// error result saying so.
+func (s *Server) execute(ctx context.Context, tool Tool, args map[string]interface{}) (*sdkmcp.CallToolResult, error) {
+	callCtx, cancel, timeout := s.toolContext(ctx, tool.Name())
+	defer cancel()
+
+	result, err := tool.Execute(callCtx, args)
+	failed := err != nil || (result != nil && result.IsError != nil && *result.IsError)
+	switch {
+	case failed && ctx.Err() != nil:
+		slog.InfoContext(ctx, "Tool cancelled", "tool", tool.Name(), "error", ctx.Err())
+		return nil, ctx.Err()
+	case failed && errors.Is(callCtx.Err(), context.DeadlineExceeded):
+		slog.WarnContext(ctx, "Tool timed out", "tool", tool.Name(), "timeout", timeout)
+		return errorResult(fmt.Errorf("%s timed out after %v", tool.Name(), timeout)), nil
+	case err != nil:
+		slog.WarnContext(ctx, "Tool failed", "tool", tool.Name(), "error", err)
+		return errorResult(err), nil
+	case failed:
+		slog.WarnContext(ctx, "Tool returned an error result", "tool", tool.Name())
+		return toCallToolResult(result), nil
+	}
+
+	slog.InfoContext(ctx, "Tool completed", "tool", tool.Name())
+	return toCallToolResult(result), nil
+}
+
+// SetToolTimeouts bounds how long tool calls may run: timeouts holds limits
+// for individual tools and def applies to the rest. Zero means no limit.
+func (s *Server) SetToolTimeouts(def time.Duration, timeouts map[string]time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolTimeout = def
+	s.toolTimeouts = timeouts
+}
+
+// toolContext derives the context a call of the named tool runs in, and
+// returns the timeout applied
+func (s *Server) toolContext(ctx context.Context, name string) (context.Context, context.CancelFunc, time.Duration) {
+	s.mu.Lock()
+	timeout, ok := s.toolTimeouts[name]
+	if !ok {
+		timeout = s.toolTimeout
+	}
+	s.mu.Unlock()
+
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, 0
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, timeout
+}
+
+// executeWithApproval runs a gated tool only after a human has approved it.
+// Clients that support elicitation are asked directly; otherwise the call is
+// stored as a pending action to be approved over HTTP or the CLI.
+func (s *Server) executeWithApproval(ctx context.Context, req *sdkmcp.CallToolRequest, tool Tool, args map[string]interface{}) (*sdkmcp.CallToolResult, error) {
+	preview, err := approval.Describe(tool.Name(), tool, args)
+	if err != nil {
+		return errorResult(err), nil
+	}
 
-		isError := result.IsError != nil && *result.IsError
-		mcpResult := &sdkmcp.CallToolResult{
-			Content: content,
-			IsError: isError,
+	if s.approvals.Elicit() && clientSupportsElicitation(req.Session) {
+		approved, err := s.elicitApproval(ctx, req.Session, preview)
+		if err == nil {
+			if !approved {
+				slog.InfoContext(ctx, "Tool declined by user", "tool", tool.Name())
+				return &sdkmcp.CallToolResult{
+					Content: []sdkmcp.Content{&sdkmcp.TextContent{Text: "The user declined this action. Nothing was sent or changed."}},
+					IsError: true,
+				}, nil
+			}
+
+			slog.InfoContext(ctx, "Tool approved by user via elicitation", "tool", tool.Name())
+			return s.execute(ctx, tool, args)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
+		slog.WarnContext(ctx, "Elicitation failed, falling back to pending approval", "error", err)
+	}
+
+	action, err := s.approvals.Submit(tool.Name(), args, preview)
+	if err != nil {
+		return errorResult(err), nil
+	}
+	return toCallToolResult(s.approvals.PendingResult(action)), nil
+}
+
+func (s *Server) elicitApproval(ctx context.Context, session *sdkmcp.ServerSession, preview string) (bool, error) {
+	res, err := session.Elicit(ctx, &sdkmcp.ElicitParams{
+		Message: preview + "\n\nDo you approve this action?",
+		RequestedSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"approve": {
+					Type:        "boolean",
+					Description: "Approve the action",
+				},
+			},
+			Required: []string{"approve"},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	approved, _ := res.Content["approve"].(bool)
+	return res.Action == "accept" && approved, nil
+}
 
-		log.Printf("Tool '%s' completed successfully", tool.Name())
-		return mcpResult, nil, nil
+func clientSupportsElicitation(session *sdkmcp.ServerSession) bool {
+	if session == nil {
+		return false
 	}
+	params := session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
 
-	// Use the global AddTool function with proper type parameters
-	sdkmcp.AddTool(s.mcpServer, toolDef, handler)
-	log.Printf("Successfully registered tool: %s", tool.Name())
+// clientName returns the name the client gave when it initialized the session
+func clientName(session *sdkmcp.ServerSession) string {
+	if params := session.InitializeParams(); params != nil && params.ClientInfo != nil {
+		return params.ClientInfo.Name
+	}
+	return ""
+}
+
+// toCallToolResult converts our internal result format to MCP format
+func toCallToolResult(result *types.ToolResult) *sdkmcp.CallToolResult {
+	var content []sdkmcp.Content
+	for _, c := range result.Content {
+		content = append(content, &sdkmcp.TextContent{Text: c.Text})
+	}
+
+	return &sdkmcp.CallToolResult{
+		Content: content,
+		IsError: result.IsError != nil && *result.IsError,
+	}
+}
+
+func errorResult(err error) *sdkmcp.CallToolResult {
+	return &sdkmcp.CallToolResult{
+		Content: []sdkmcp.Content{&sdkmcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+		IsError: true,
+	}
 }
 
 func (s *Server) Run(ctx context.Context, transport sdkmcp.Transport) error {
-	log.Printf("Starting MCP server with transport...")
-	return s.mcpServer.Run(ctx, transport)
+	slog.Debug("Starting MCP server")
+	return s.mcpServer.Run(ctx, methodNotFoundTransport{transport})
 }
@@ -0,0 +1,210 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// ResourceTemplate serves every resource whose URI matches an RFC 6570 URI
+// template
+type ResourceTemplate interface {
+	Name() string
+	Description() string
+	URITemplate() string
+	MIMEType() string
+	// Subscribable reports whether clients may subscribe to uri to be told
+	// when it changes
+	Subscribable(uri string) bool
+	Read(ctx context.Context, uri string) ([]types.ResourceContent, error)
+}
+
+// registeredTemplate is a ResourceTemplate with its compiled URI template
+type registeredTemplate struct {
+	ResourceTemplate
+	pattern *uritemplate.Template
+}
+
+// SetResources makes templates and resources the complete set of resources
+// offered. Each resource in resources is read through the template matching
+// its URI and is listed in resources/list; the templates also serve any
+// other matching URI.
+func (s *Server) SetResources(templates []ResourceTemplate, resources []types.Resource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var registered []registeredTemplate
+	wantedTemplates := make(map[string]ResourceTemplate)
+	for _, t := range templates {
+		pattern, err := uritemplate.New(t.URITemplate())
+		if err != nil {
+			slog.Error("Not registering resource template", "template", t.URITemplate(), "error", err)
+			continue
+		}
+		registered = append(registered, registeredTemplate{t, pattern})
+		wantedTemplates[t.URITemplate()] = t
+		if s.resourceTemplates[t.URITemplate()] != t {
+			s.mcpServer.AddResourceTemplate(&sdkmcp.ResourceTemplate{
+				Name:        t.Name(),
+				Description: t.Description(),
+				URITemplate: t.URITemplate(),
+				MIMEType:    t.MIMEType(),
+			}, s.readResource)
+		}
+	}
+	s.templates = registered
+
+	wantedResources := make(map[string]types.Resource)
+	for _, r := range resources {
+		if _, ok := s.templateFor(r.URI); !ok {
+			slog.Error("Not registering resource: no resource template matches it", "uri", r.URI)
+			continue
+		}
+		wantedResources[r.URI] = r
+		if existing, ok := s.resources[r.URI]; !ok || existing != r {
+			s.mcpServer.AddResource(&sdkmcp.Resource{
+				URI:         r.URI,
+				Name:        r.Name,
+				Description: r.Description,
+				MIMEType:    r.MIMEType,
+			}, s.readResource)
+		}
+	}
+
+	var removedTemplates, removedResources []string
+	for uri := range s.resourceTemplates {
+		if _, ok := wantedTemplates[uri]; !ok {
+			removedTemplates = append(removedTemplates, uri)
+		}
+	}
+	for uri := range s.resources {
+		if _, ok := wantedResources[uri]; !ok {
+			removedResources = append(removedResources, uri)
+		}
+	}
+	if len(removedTemplates) > 0 {
+		s.mcpServer.RemoveResourceTemplates(removedTemplates...)
+	}
+	if len(removedResources) > 0 {
+		s.mcpServer.RemoveResources(removedResources...)
+	}
+	s.resourceTemplates = wantedTemplates
+	s.resources = wantedResources
+}
+
+// templateFor returns the template serving uri. s.mu must be held.
+func (s *Server) templateFor(uri string) (ResourceTemplate, bool) {
+	for _, t := range s.templates {
+		if t.pattern.Regexp().MatchString(uri) {
+			return t.ResourceTemplate, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) lookupTemplate(uri string) (ResourceTemplate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.templateFor(uri)
+}
+
+func (s *Server) readResource(ctx context.Context, req *sdkmcp.ReadResourceRequest) (*sdkmcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	t, ok := s.lookupTemplate(uri)
+	if !ok {
+		return nil, sdkmcp.ResourceNotFoundError(uri)
+	}
+
+	ctx = withLogSession(ctx, req.Session)
+	slog.InfoContext(ctx, "Reading resource", "uri", uri)
+	ctx = withProgress(ctx, req.Session, req.Params.GetProgressToken())
+	contents, err := t.Read(ctx, uri)
+	if err != nil {
+		slog.WarnContext(ctx, "Resource read failed", "uri", uri, "error", err)
+		return nil, err
+	}
+
+	result := &sdkmcp.ReadResourceResult{}
+	for _, c := range contents {
+		result.Contents = append(result.Contents, &sdkmcp.ResourceContents{
+			URI:      c.URI,
+			MIMEType: c.MIMEType,
+			Text:     c.Text,
+			Blob:     c.Blob,
+		})
+	}
+	return result, nil
+}
+
+func (s *Server) subscribe(ctx context.Context, req *sdkmcp.SubscribeRequest) error {
+	uri := req.Params.URI
+	t, ok := s.lookupTemplate(uri)
+	if !ok {
+		return sdkmcp.ResourceNotFoundError(uri)
+	}
+	if !t.Subscribable(uri) {
+		return fmt.Errorf("resource %s does not support subscriptions", uri)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions[uri] == nil {
+		s.subscriptions[uri] = make(map[*sdkmcp.ServerSession]bool)
+	}
+	s.subscriptions[uri][req.Session] = true
+	slog.InfoContext(withLogSession(ctx, req.Session), "Client subscribed", "uri", uri)
+	return nil
+}
+
+func (s *Server) unsubscribe(ctx context.Context, req *sdkmcp.UnsubscribeRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sessions, ok := s.subscriptions[req.Params.URI]; ok {
+		delete(sessions, req.Session)
+		if len(sessions) == 0 {
+			delete(s.subscriptions, req.Params.URI)
+		}
+	}
+	return nil
+}
+
+// Subscriptions returns the URIs at least one connected client is
+// subscribed to
+func (s *Server) Subscriptions() []string {
+	live := make(map[*sdkmcp.ServerSession]bool)
+	for session := range s.mcpServer.Sessions() {
+		live[session] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var uris []string
+	for uri, sessions := range s.subscriptions {
+		for session := range sessions {
+			if !live[session] {
+				delete(sessions, session)
+			}
+		}
+		if len(sessions) == 0 {
+			delete(s.subscriptions, uri)
+			continue
+		}
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// ResourceUpdated notifies the clients subscribed to uri that it changed
+func (s *Server) ResourceUpdated(uri string) {
+	slog.Debug("Resource updated", "uri", uri)
+	if err := s.mcpServer.ResourceUpdated(context.Background(), &sdkmcp.ResourceUpdatedNotificationParams{URI: uri}); err != nil {
+		slog.Warn("Failed to notify subscribers", "uri", uri, "error", err)
+	}
+}
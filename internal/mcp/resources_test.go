@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// noteTemplate serves note://{name}; only note://inbox may be subscribed to
+type noteTemplate struct{}
+
+func (noteTemplate) Name() string        { return "note" }
+func (noteTemplate) Description() string { return "A note" }
+func (noteTemplate) URITemplate() string { return "note://{name}" }
+func (noteTemplate) MIMEType() string    { return "text/plain" }
+func (noteTemplate) Subscribable(uri string) bool {
+	return uri == "note://inbox"
+}
+func (noteTemplate) Read(ctx context.Context, uri string) ([]types.ResourceContent, error) {
+	return []types.ResourceContent{
+		{URI: uri, MIMEType: "text/plain", Text: "contents of " + uri},
+		{URI: uri, MIMEType: "message/rfc822", Blob: []byte("raw")},
+	}, nil
+}
+
+func TestResources(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	server.SetResources([]ResourceTemplate{noteTemplate{}}, []types.Resource{
+		{URI: "note://inbox", Name: "inbox", MIMEType: "text/plain"},
+		{URI: "other://x", Name: "unmatched"},
+	})
+
+	updated := make(chan string, 1)
+	session := connect(t, server, &sdkmcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *sdkmcp.ResourceUpdatedNotificationRequest) {
+			updated <- req.Params.URI
+		},
+	})
+
+	templates, err := session.ListResourceTemplates(ctx, nil)
+	if err != nil || len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate != "note://{name}" {
+		t.Fatalf("expected the note template, got %+v, %v", templates, err)
+	}
+	resources, err := session.ListResources(ctx, nil)
+	if err != nil || len(resources.Resources) != 1 || resources.Resources[0].URI != "note://inbox" {
+		t.Fatalf("expected only the matched resource, got %+v, %v", resources, err)
+	}
+
+	res, err := session.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: "note://draft"})
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	if len(res.Contents) != 2 || res.Contents[0].Text != "contents of note://draft" || string(res.Contents[1].Blob) != "raw" {
+		t.Errorf("unexpected contents %+v", res.Contents)
+	}
+	if _, err := session.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: "other://x"}); err == nil {
+		t.Error("expected reading an unmatched URI to fail")
+	}
+
+	if err := session.Subscribe(ctx, &sdkmcp.SubscribeParams{URI: "note://draft"}); err == nil {
+		t.Error("expected subscribing to a resource without subscriptions to fail")
+	}
+	if err := session.Subscribe(ctx, &sdkmcp.SubscribeParams{URI: "note://inbox"}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if got := server.Subscriptions(); len(got) != 1 || got[0] != "note://inbox" {
+		t.Fatalf("Subscriptions() = %v", got)
+	}
+
+	server.ResourceUpdated("note://inbox")
+	select {
+	case uri := <-updated:
+		if uri != "note://inbox" {
+			t.Errorf("notified of %s", uri)
+		}
+	case <-ctx.Done():
+		t.Fatal("expected notifications/resources/updated")
+	}
+
+	server.SetResources(nil, nil)
+	if resources, err := session.ListResources(ctx, nil); err != nil || len(resources.Resources) != 0 {
+		t.Errorf("expected no resources after removal, got %+v, %v", resources, err)
+	}
+}
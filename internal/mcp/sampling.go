@@ -0,0 +1,48 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"ai-presence-mcp/internal/sampling"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// withSampling lets tools sample from the model of the client behind
+// session with sampling/createMessage, if the client declared the sampling
+// capability
+func withSampling(ctx context.Context, session *sdkmcp.ServerSession) context.Context {
+	if !clientSupportsSampling(session) {
+		return ctx
+	}
+
+	return sampling.WithSampler(ctx, func(ctx context.Context, req sampling.Request) (string, error) {
+		res, err := session.CreateMessage(ctx, &sdkmcp.CreateMessageParams{
+			Messages: []*sdkmcp.SamplingMessage{{
+				Role:    "user",
+				Content: &sdkmcp.TextContent{Text: req.Prompt},
+			}},
+			SystemPrompt: req.SystemPrompt,
+			MaxTokens:    int64(req.MaxTokens),
+			// The prompt carries everything the model needs
+			IncludeContext: "none",
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to sample from the client: %w", err)
+		}
+		text, ok := res.Content.(*sdkmcp.TextContent)
+		if !ok {
+			return "", fmt.Errorf("client returned %T content instead of text", res.Content)
+		}
+		return text.Text, nil
+	})
+}
+
+func clientSupportsSampling(session *sdkmcp.ServerSession) bool {
+	if session == nil {
+		return false
+	}
+	params := session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Sampling != nil
+}
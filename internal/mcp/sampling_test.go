@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/internal/sampling"
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// samplingTool answers with what the client's model said, or that it could not ask
+type samplingTool struct{}
+
+func (t *samplingTool) Name() string             { return "ask" }
+func (t *samplingTool) Description() string      { return "ask" }
+func (t *samplingTool) InputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (t *samplingTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	text, err := sampling.Sample(ctx, sampling.Request{SystemPrompt: "Be brief", Prompt: "ping", MaxTokens: 10})
+	if errors.Is(err, sampling.ErrUnsupported) {
+		text = "unsupported"
+	} else if err != nil {
+		return nil, err
+	}
+	return &types.ToolResult{Content: []types.ToolContent{{Type: "text", Text: text}}}, nil
+}
+
+func TestToolsSampleFromClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	server.RegisterTool(&samplingTool{})
+
+	call := func(opts *sdkmcp.ClientOptions) string {
+		session := connect(t, server, opts)
+
+		res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "ask"})
+		if err != nil {
+			t.Fatalf("CallTool: %v", err)
+		}
+		return res.Content[0].(*sdkmcp.TextContent).Text
+	}
+
+	got := call(&sdkmcp.ClientOptions{
+		CreateMessageHandler: func(_ context.Context, req *sdkmcp.CreateMessageRequest) (*sdkmcp.CreateMessageResult, error) {
+			p := req.Params
+			prompt, _ := p.Messages[0].Content.(*sdkmcp.TextContent)
+			if prompt == nil || prompt.Text != "ping" || p.SystemPrompt != "Be brief" || p.MaxTokens != 10 {
+				t.Errorf("unexpected request %+v", p)
+			}
+			return &sdkmcp.CreateMessageResult{Role: "assistant", Model: "test", Content: &sdkmcp.TextContent{Text: "pong"}}, nil
+		},
+	})
+	if got != "pong" {
+		t.Errorf("expected the client's answer, got %q", got)
+	}
+
+	if got := call(nil); got != "unsupported" {
+		t.Errorf("expected sampling to be unsupported without a handler, got %q", got)
+	}
+}
@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// toolSchema converts the input schema a tool declares, either a
+// *jsonschema.Schema or its JSON form as nested maps, and resolves it for
+// validation
+func toolSchema(tool Tool) (*jsonschema.Schema, *jsonschema.Resolved, error) {
+	data, err := json.Marshal(tool.InputSchema())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode input schema of %s: %w", tool.Name(), err)
+	}
+
+	schema := &jsonschema.Schema{}
+	if string(data) != "null" {
+		if err := json.Unmarshal(data, schema); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse input schema of %s: %w", tool.Name(), err)
+		}
+	}
+	if schema.Type == "" {
+		schema.Type = "object"
+	}
+	if schema.Type != "object" {
+		return nil, nil, fmt.Errorf("input schema of %s must have type object, got %q", tool.Name(), schema.Type)
+	}
+
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid input schema of %s: %w", tool.Name(), err)
+	}
+	return schema, resolved, nil
+}
+
+// decodeArguments parses the arguments of a tool call, which the SDK leaves
+// as raw JSON, and validates them against the tool's schema. When the schema
+// lists its properties and says nothing about others, an unlisted argument is
+// rejected so that a misspelled name is reported rather than silently ignored.
+func decodeArguments(arguments any, resolved *jsonschema.Resolved) (map[string]interface{}, error) {
+	raw, ok := arguments.(json.RawMessage)
+	if !ok && arguments != nil {
+		data, err := json.Marshal(arguments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode arguments: %w", err)
+		}
+		raw = data
+	}
+
+	args := make(map[string]interface{})
+	if raw = bytes.TrimSpace(raw); len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+	}
+
+	schema := resolved.Schema()
+	if schema.Properties != nil && schema.AdditionalProperties == nil {
+		var unknown []string
+		for name := range args {
+			if _, ok := schema.Properties[name]; !ok {
+				unknown = append(unknown, name)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("unknown argument %q (accepted: %s)", unknown[0], propertyNames(schema))
+		}
+	}
+
+	if err := resolved.Validate(args); err != nil {
+		return nil, validationError(err, schema)
+	}
+	return args, nil
+}
+
+// validationError rewrites a jsonschema-go error, which nests one
+// "validating <schema path>" prefix per level, into the name of the argument
+// at fault followed by the reason
+func validationError(err error, schema *jsonschema.Schema) error {
+	msg := err.Error()
+	var argument string
+	for {
+		rest, ok := strings.CutPrefix(msg, "validating ")
+		if !ok {
+			break
+		}
+		path, tail, ok := strings.Cut(rest, ": ")
+		if !ok {
+			break
+		}
+		if name, ok := strings.CutPrefix(path, "/properties/"); ok && argument == "" {
+			argument = name
+		}
+		msg = tail
+	}
+
+	if argument != "" {
+		return fmt.Errorf("argument %q: %s", argument, msg)
+	}
+	if strings.HasPrefix(msg, "anyOf:") {
+		if alternatives := requiredAlternatives(schema); alternatives != nil {
+			return fmt.Errorf("one of the arguments %s is required", strings.Join(alternatives, " or "))
+		}
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// requiredAlternatives returns the quoted property names of an anyOf whose
+// branches each only require one property, the way a schema says that one
+// of several aliases must be given
+func requiredAlternatives(schema *jsonschema.Schema) []string {
+	var names []string
+	for _, branch := range schema.AnyOf {
+		if len(branch.Required) != 1 {
+			return nil
+		}
+		names = append(names, fmt.Sprintf("%q", branch.Required[0]))
+	}
+	return names
+}
+
+func propertyNames(schema *jsonschema.Schema) string {
+	if len(schema.Properties) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,202 @@
+package mcp
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type stubTool struct{ name string }
+
+func (t *stubTool) Name() string             { return t.name }
+func (t *stubTool) Description() string      { return "stub" }
+func (t *stubTool) InputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (t *stubTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	return &types.ToolResult{Content: []types.ToolContent{{Type: "text", Text: t.name}}}, nil
+}
+
+// connect opens a client session with opts on an in-memory transport to
+// server, closed when the test ends
+func connect(t *testing.T, server *Server, opts *sdkmcp.ClientOptions) *sdkmcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := sdkmcp.NewInMemoryTransports()
+	if _, err := server.mcpServer.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server Connect: %v", err)
+	}
+	session, err := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test"}, opts).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client Connect: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestSetToolsNotifiesClients(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	a, b := &stubTool{"a"}, &stubTool{"b"}
+	server.SetTools([]Tool{a, b})
+
+	changed := make(chan struct{}, 4)
+	session := connect(t, server, &sdkmcp.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *sdkmcp.ToolListChangedRequest) {
+			changed <- struct{}{}
+		},
+	})
+
+	listTools := func() []string {
+		t.Helper()
+		res, err := session.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatalf("ListTools: %v", err)
+		}
+		var names []string
+		for _, tool := range res.Tools {
+			names = append(names, tool.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if got := listTools(); len(got) != 2 {
+		t.Fatalf("expected tools a and b, got %v", got)
+	}
+
+	server.SetTools([]Tool{a})
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatal("expected notifications/tools/list_changed")
+	}
+	if got := listTools(); len(got) != 1 || got[0] != "a" {
+		t.Errorf("expected only tool a after removal, got %v", got)
+	}
+}
+
+type sendTool struct{ calls int }
+
+func (t *sendTool) Name() string        { return "send" }
+func (t *sendTool) Description() string { return "send" }
+func (t *sendTool) InputSchema() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"to":    map[string]interface{}{"type": "string"},
+			"count": map[string]interface{}{"type": "integer", "minimum": 1},
+		},
+		"required": []string{"to"},
+	}
+}
+func (t *sendTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	t.calls++
+	return &types.ToolResult{Content: []types.ToolContent{{Type: "text", Text: "sent"}}}, nil
+}
+
+func TestToolArgumentsValidated(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	tool := &sendTool{}
+	server.RegisterTool(tool)
+
+	session := connect(t, server, nil)
+
+	list, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(list.Tools) != 1 || list.Tools[0].InputSchema == nil ||
+		len(list.Tools[0].InputSchema.Required) != 1 || list.Tools[0].InputSchema.Required[0] != "to" {
+		t.Fatalf("expected the tool's schema requiring to, got %+v", list.Tools[0].InputSchema)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{}, `missing properties: ["to"]`},
+		{map[string]any{"to": 5}, `argument "to": type: 5 has type "integer", want "string"`},
+		{map[string]any{"to": "a@example.com", "count": 0}, `argument "count": minimum`},
+		{map[string]any{"to": "a@example.com", "recipient": "b"}, `unknown argument "recipient" (accepted: count, to)`},
+	}
+	for _, tt := range tests {
+		res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "send", Arguments: tt.args})
+		if err != nil {
+			t.Fatalf("CallTool(%v): %v", tt.args, err)
+		}
+		text := res.Content[0].(*sdkmcp.TextContent).Text
+		if !res.IsError || !strings.Contains(text, tt.want) {
+			t.Errorf("CallTool(%v) = %q, want an error containing %q", tt.args, text, tt.want)
+		}
+	}
+	if tool.calls != 0 {
+		t.Fatalf("Execute ran %d times with invalid arguments", tool.calls)
+	}
+
+	res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "send", Arguments: map[string]any{"to": "a@example.com", "count": 2}})
+	if err != nil || res.IsError || tool.calls != 1 {
+		t.Fatalf("expected a valid call to run, got %+v, %v", res, err)
+	}
+}
+
+// blockingTool runs until its context ends
+type blockingTool struct{ started chan struct{} }
+
+func (t *blockingTool) Name() string             { return "slow" }
+func (t *blockingTool) Description() string      { return "slow" }
+func (t *blockingTool) InputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (t *blockingTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	t.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestToolTimeoutAndCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	tool := &blockingTool{started: make(chan struct{}, 2)}
+	server.RegisterTool(tool)
+	server.SetToolTimeouts(time.Hour, map[string]time.Duration{"slow": 50 * time.Millisecond})
+
+	session := connect(t, server, nil)
+
+	res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "slow"})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if text := res.Content[0].(*sdkmcp.TextContent).Text; !res.IsError || !strings.Contains(text, "slow timed out after 50ms") {
+		t.Errorf("expected a timeout error, got %q", text)
+	}
+	<-tool.started
+
+	// Without a timeout the call runs until the client cancels it
+	server.SetToolTimeouts(0, nil)
+	callCtx, cancelCall := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		_, err := session.CallTool(callCtx, &sdkmcp.CallToolParams{Name: "slow"})
+		done <- err
+	}()
+	<-tool.started
+	cancelCall()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the cancelled call to fail")
+		}
+	case <-ctx.Done():
+		t.Fatal("cancelling the call did not stop the tool")
+	}
+}
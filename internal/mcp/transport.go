@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Transports the server can be reached over
+const (
+	TransportStdio          = "stdio"
+	TransportStreamableHTTP = "streamable-http"
+	TransportSSE            = "sse"
+)
+
+// Transports lists the valid transport names
+var Transports = []string{TransportStdio, TransportStreamableHTTP, TransportSSE}
+
+// HTTPPath is where the HTTP transports serve MCP
+const HTTPPath = "/mcp"
+
+// handleMessageTimeout bounds a HandleMessage call
+const handleMessageTimeout = 30 * time.Second
+
+// notHandledPrefix starts the error the SDK answers unknown methods with
+const notHandledPrefix = "JSON RPC not handled"
+
+// errMethodNotFound carries the JSON-RPC "method not found" code, which the
+// SDK leaves out of its answer to unknown methods. It is decoded from the
+// wire since the SDK does not export a way to build an error with a code.
+var errMethodNotFound = func() error {
+	msg, err := jsonrpc.DecodeMessage([]byte(`{"jsonrpc":"2.0","id":0,"error":{"code":-32601,"message":"method not found"}}`))
+	if err != nil {
+		panic(err)
+	}
+	return msg.(*jsonrpc.Response).Error
+}()
+
+// CheckTransport returns an error unless transport names a valid transport
+func CheckTransport(transport string) error {
+	for _, t := range Transports {
+		if transport == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown transport %q (valid: %s)", transport, strings.Join(Transports, ", "))
+}
+
+// HTTPHandler returns an HTTP handler serving this server over the
+// streamable HTTP or SSE transport. Every client gets its own session,
+// identified by the Mcp-Session-Id header or the SSE message endpoint, but
+// all sessions share the same tools, resources and prompts.
+func (s *Server) HTTPHandler(transport string) (http.Handler, error) {
+	getServer := func(*http.Request) *sdkmcp.Server { return s.mcpServer }
+
+	switch transport {
+	case TransportStreamableHTTP:
+		return sdkmcp.NewStreamableHTTPHandler(getServer, nil), nil
+	case TransportSSE:
+		return sdkmcp.NewSSEHandler(getServer), nil
+	}
+	if err := CheckTransport(transport); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("transport %s is not served over HTTP", transport)
+}
+
+// HandleMessage answers one JSON-RPC request on a short-lived session that
+// starts out initialized, so any method may be called without a handshake.
+// It returns nil for a notification.
+func (s *Server) HandleMessage(msg []byte) ([]byte, error) {
+	decoded, err := jsonrpc.DecodeMessage(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	req, ok := decoded.(*jsonrpc.Request)
+	if !ok {
+		return nil, fmt.Errorf("message is not a request")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handleMessageTimeout)
+	defer cancel()
+
+	serverTransport, clientTransport := sdkmcp.NewInMemoryTransports()
+	session, err := s.mcpServer.Connect(ctx, methodNotFoundTransport{serverTransport}, &sdkmcp.ServerSessionOptions{
+		State: &sdkmcp.ServerSessionState{
+			InitializeParams:  &sdkmcp.InitializeParams{ClientInfo: &sdkmcp.Implementation{Name: "HandleMessage"}},
+			InitializedParams: &sdkmcp.InitializedParams{},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	defer session.Close()
+
+	conn, err := clientTransport.Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Write(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	if !req.IsCall() {
+		return nil, nil
+	}
+
+	// Skip any notifications sent before the response
+	for {
+		reply, err := conn.Read(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp, ok := reply.(*jsonrpc.Response); ok && resp.ID == req.ID {
+			return jsonrpc.EncodeMessage(resp)
+		}
+	}
+}
+
+// methodNotFoundTransport gives the SDK's answers to unknown methods the
+// -32601 code clients expect
+type methodNotFoundTransport struct {
+	sdkmcp.Transport
+}
+
+func (t methodNotFoundTransport) Connect(ctx context.Context) (sdkmcp.Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return methodNotFoundConn{conn}, nil
+}
+
+type methodNotFoundConn struct {
+	sdkmcp.Connection
+}
+
+func (c methodNotFoundConn) Write(ctx context.Context, msg jsonrpc.Message) error {
+	if resp, ok := msg.(*jsonrpc.Response); ok && resp.Error != nil {
+		if detail, found := strings.CutPrefix(resp.Error.Error(), notHandledPrefix); found {
+			resp.Error = fmt.Errorf("%w%s", errMethodNotFound, detail)
+		}
+	}
+	return c.Connection.Write(ctx, msg)
+}
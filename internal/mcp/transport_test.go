@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/internal/progress"
+	"ai-presence-mcp/pkg/types"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestHTTPTransportsServeTools(t *testing.T) {
+	server := NewServer()
+	server.SetTools([]Tool{&stubTool{"a"}})
+
+	for _, tc := range []struct {
+		transport string
+		client    func(url string) sdkmcp.Transport
+	}{
+		{TransportStreamableHTTP, func(url string) sdkmcp.Transport { return sdkmcp.NewStreamableClientTransport(url, nil) }},
+		{TransportSSE, func(url string) sdkmcp.Transport { return sdkmcp.NewSSEClientTransport(url, nil) }},
+	} {
+		t.Run(tc.transport, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			handler, err := server.HTTPHandler(tc.transport)
+			if err != nil {
+				t.Fatalf("HTTPHandler: %v", err)
+			}
+			httpServer := httptest.NewServer(handler)
+			defer httpServer.Close()
+
+			client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test"}, nil)
+			session, err := client.Connect(ctx, tc.client(httpServer.URL), nil)
+			if err != nil {
+				t.Fatalf("client Connect: %v", err)
+			}
+			defer session.Close()
+
+			tools, err := session.ListTools(ctx, nil)
+			if err != nil {
+				t.Fatalf("ListTools: %v", err)
+			}
+			if len(tools.Tools) != 1 || tools.Tools[0].Name != "a" {
+				t.Errorf("expected tool a, got %v", tools.Tools)
+			}
+
+			res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "a"})
+			if err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+			if text, ok := res.Content[0].(*sdkmcp.TextContent); !ok || text.Text != "a" {
+				t.Errorf("unexpected result %v", res.Content)
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerRejectsStdio(t *testing.T) {
+	server := NewServer()
+	if _, err := server.HTTPHandler(TransportStdio); err == nil {
+		t.Error("expected an error for stdio")
+	}
+	if _, err := server.HTTPHandler("websocket"); err == nil {
+		t.Error("expected an error for an unknown transport")
+	}
+}
+
+// stepsTool reports each of its steps as progress
+type stepsTool struct{ steps int }
+
+func (t *stepsTool) Name() string             { return "steps" }
+func (t *stepsTool) Description() string      { return "steps" }
+func (t *stepsTool) InputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (t *stepsTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	for i := 1; i <= t.steps; i++ {
+		progress.Report(ctx, float64(i), float64(t.steps), fmt.Sprintf("step %d", i))
+		// Reporting the same progress again must not reach the client
+		progress.Report(ctx, float64(i), float64(t.steps), "again")
+	}
+	return &types.ToolResult{Content: []types.ToolContent{{Type: "text", Text: "done"}}}, nil
+}
+
+func TestProgressOverStreamableHTTP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer()
+	server.SetTools([]Tool{&stepsTool{steps: 3}})
+	handler, err := server.HTTPHandler(TransportStreamableHTTP)
+	if err != nil {
+		t.Fatalf("HTTPHandler: %v", err)
+	}
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	updates := make(chan *sdkmcp.ProgressNotificationParams, 10)
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test"}, &sdkmcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *sdkmcp.ProgressNotificationClientRequest) {
+			updates <- req.Params
+		},
+	})
+	session, err := client.Connect(ctx, sdkmcp.NewStreamableClientTransport(httpServer.URL, nil), nil)
+	if err != nil {
+		t.Fatalf("client Connect: %v", err)
+	}
+	defer session.Close()
+
+	// SetProgressToken loses the token unless Meta is already set
+	params := &sdkmcp.CallToolParams{Name: "steps", Meta: sdkmcp.Meta{}}
+	params.SetProgressToken("call-1")
+	if _, err := session.CallTool(ctx, params); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+
+	// Updates within progressInterval of each other are dropped, but the
+	// first and the one completing the work always arrive
+	var got []float64
+	for len(got) == 0 || got[len(got)-1] < 3 {
+		select {
+		case p := <-updates:
+			if p.ProgressToken != "call-1" || p.Total != 3 || p.Message == "again" {
+				t.Errorf("unexpected update %+v", p)
+			}
+			if len(got) > 0 && p.Progress <= got[len(got)-1] {
+				t.Errorf("progress went from %v to %v", got[len(got)-1], p.Progress)
+			}
+			got = append(got, p.Progress)
+		case <-ctx.Done():
+			t.Fatalf("expected progress to reach 3, got %v", got)
+		}
+	}
+	if got[0] != 1 {
+		t.Errorf("expected the first update to be 1, got %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"ai-presence-mcp/pkg/types"
+
+	"golang.org/x/oauth2"
+)
+
+// Login runs the authorization code flow with PKCE for an account. It listens
+// on a loopback redirect URL, passes the authorization URL to open (which
+// should show it to the user or launch a browser) and stores the resulting
+// refresh token. It returns once the redirect arrives or ctx is done.
+func Login(ctx context.Context, account string, config *types.OAuthConfig, store *Store, open func(authURL string)) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", config.RedirectPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for the OAuth redirect: %w", err)
+	}
+	defer listener.Close()
+
+	redirectURL := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+	client, err := clientConfig(config, redirectURL)
+	if err != nil {
+		return err
+	}
+
+	state, err := randomState()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		var res result
+		switch {
+		case query.Get("state") != state:
+			res.err = errors.New("OAuth redirect has an unexpected state")
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("authorization was refused: %s %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("code") == "":
+			res.err = errors.New("OAuth redirect is missing the authorization code")
+		default:
+			res.code = query.Get("code")
+		}
+
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Authorization complete. You can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	open(client.AuthCodeURL(state,
+		oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("prompt", "consent"),
+		oauth2.SetAuthURLParam("login_hint", account),
+		oauth2.S256ChallengeOption(verifier),
+	))
+
+	var res result
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for the OAuth redirect: %w", ctx.Err())
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	token, err := client.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	if token.RefreshToken == "" {
+		return errors.New("the authorization server did not return a refresh token")
+	}
+
+	return store.SaveRefreshToken(account, token.RefreshToken)
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
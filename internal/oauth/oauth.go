@@ -0,0 +1,222 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+
+	"golang.org/x/oauth2"
+)
+
+// refreshTimeout bounds a token refresh request, so a refresh left running
+// in the background after its caller gave up still ends
+const refreshTimeout = 30 * time.Second
+
+// refreshClient makes the token refresh requests
+var refreshClient = &http.Client{Timeout: refreshTimeout}
+
+// provider holds the endpoints and scopes of a well-known mail provider
+type provider struct {
+	endpoint oauth2.Endpoint
+	scopes   []string
+}
+
+var providers = map[string]provider{
+	"google": {
+		endpoint: oauth2.Endpoint{
+			AuthURL:  "https://accounts.google.com/o/oauth2/auth",
+			TokenURL: "https://oauth2.googleapis.com/token",
+		},
+		scopes: []string{"https://mail.google.com/"},
+	},
+	"microsoft": {
+		endpoint: oauth2.Endpoint{
+			AuthURL:  "https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
+			TokenURL: "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+		},
+		scopes: []string{
+			"https://outlook.office.com/IMAP.AccessAsUser.All",
+			"https://outlook.office.com/SMTP.Send",
+			"offline_access",
+		},
+	},
+}
+
+// clientConfig builds the oauth2 client configuration for an account. Explicit
+// endpoints and scopes take precedence over the provider preset.
+func clientConfig(config *types.OAuthConfig, redirectURL string) (*oauth2.Config, error) {
+	var preset provider
+	if config.Provider != "" {
+		p, ok := providers[strings.ToLower(config.Provider)]
+		if !ok {
+			return nil, fmt.Errorf("unknown OAuth provider %q", config.Provider)
+		}
+		preset = p
+	}
+
+	endpoint := preset.endpoint
+	if config.AuthURL != "" {
+		endpoint.AuthURL = config.AuthURL
+	}
+	if config.TokenURL != "" {
+		endpoint.TokenURL = config.TokenURL
+	}
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" {
+		return nil, fmt.Errorf("OAuth config needs a provider or both auth_url and token_url")
+	}
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("OAuth config is missing client_id")
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = preset.scopes
+	}
+
+	return &oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		Endpoint:     endpoint,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+	}, nil
+}
+
+// Manager hands out access tokens for accounts, refreshing them from the
+// stored refresh tokens as they expire
+type Manager struct {
+	store *Store
+
+	mu      sync.Mutex
+	sources map[string]oauth2.TokenSource
+}
+
+func NewManager(store *Store) *Manager {
+	return &Manager{
+		store:   store,
+		sources: make(map[string]oauth2.TokenSource),
+	}
+}
+
+// AccessToken returns a valid access token for the account, refreshing it
+// if it has expired. If ctx ends first the refresh is left to finish in the
+// background, so its token is still cached for the next call.
+func (m *Manager) AccessToken(ctx context.Context, config *types.EmailConfig) (string, error) {
+	if config.OAuth == nil {
+		return "", fmt.Errorf("account %s has no OAuth configuration", config.Username)
+	}
+
+	source, err := m.source(config)
+	if err != nil {
+		return "", err
+	}
+
+	type refreshed struct {
+		token *oauth2.Token
+		err   error
+	}
+	done := make(chan refreshed, 1)
+	go func() {
+		token, err := source.Token()
+		done <- refreshed{token, err}
+	}()
+
+	var token *oauth2.Token
+	select {
+	case r := <-done:
+		token, err = r.token, r.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("failed to refresh OAuth token for %s: %w", config.Username, ctx.Err())
+	}
+	if err != nil {
+		// Rebuild from the store next time, in case a new login replaced
+		// a revoked refresh token
+		m.Forget(config.Username)
+		return "", fmt.Errorf("failed to refresh OAuth token for %s: %w", config.Username, err)
+	}
+	return token.AccessToken, nil
+}
+
+// Forget drops the cached token source of an account, e.g. after a new login
+func (m *Manager) Forget(account string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sources, account)
+}
+
+func (m *Manager) source(config *types.EmailConfig) (oauth2.TokenSource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if source, ok := m.sources[config.Username]; ok {
+		return source, nil
+	}
+
+	client, err := clientConfig(config.OAuth, "")
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := m.store.RefreshToken(config.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, refreshClient)
+	base := client.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken})
+	source := &persistingSource{
+		base:         base,
+		store:        m.store,
+		account:      config.Username,
+		refreshToken: refreshToken,
+	}
+	m.sources[config.Username] = source
+	return source, nil
+}
+
+// persistingSource saves rotated refresh tokens back to the store
+type persistingSource struct {
+	base    oauth2.TokenSource
+	store   *Store
+	account string
+
+	mu           sync.Mutex
+	refreshToken string
+}
+
+func (s *persistingSource) Token() (*oauth2.Token, error) {
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.RefreshToken != "" && token.RefreshToken != s.refreshToken {
+		if err := s.store.SaveRefreshToken(s.account, token.RefreshToken); err != nil {
+			return nil, err
+		}
+		s.refreshToken = token.RefreshToken
+	}
+	return token, nil
+}
+
+// Configured reports whether any account has an OAuth block
+func Configured(accounts []types.EmailConfig) bool {
+	for i := range accounts {
+		if accounts[i].OAuth != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownProvider reports whether name is a built-in provider preset
+func KnownProvider(name string) bool {
+	_, ok := providers[strings.ToLower(name)]
+	return ok
+}
@@ -0,0 +1,196 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+// fakeAuthServer is a stand-in authorization server that approves every
+// request, checks PKCE and rotates refresh tokens
+type fakeAuthServer struct {
+	t         *testing.T
+	challenge string
+	refreshes int
+}
+
+func (f *fakeAuthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/authorize":
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			f.t.Errorf("expected a S256 PKCE challenge, got %q", q.Get("code_challenge_method"))
+		}
+		f.challenge = q.Get("code_challenge")
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {"auth-code"}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+
+	case "/token":
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access-0", "refresh_token": "refresh-0", "token_type": "Bearer", "expires_in": 3600,
+			})
+		case "refresh_token":
+			f.refreshes++
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "access-" + r.Form.Get("refresh_token"),
+				"refresh_token": "refresh-rotated",
+				"token_type":    "Bearer",
+				"expires_in":    3600,
+			})
+		default:
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	fake := &fakeAuthServer{t: t}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	dir := t.TempDir()
+	storeConfig := types.TokenStoreConfig{
+		Path:    filepath.Join(dir, "tokens.json"),
+		KeyPath: filepath.Join(dir, "tokens.key"),
+	}
+	store, err := OpenStore(storeConfig)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+
+	account := types.EmailConfig{
+		Username: "agent@example.com",
+		Auth:     "xoauth2",
+		OAuth: &types.OAuthConfig{
+			ClientID: "client",
+			AuthURL:  server.URL + "/authorize",
+			TokenURL: server.URL + "/token",
+			Scopes:   []string{"mail"},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = Login(ctx, account.Username, account.OAuth, store, func(authURL string) {
+		resp, err := http.Get(authURL)
+		if err != nil {
+			t.Errorf("following the authorization URL: %v", err)
+			return
+		}
+		resp.Body.Close()
+	})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	data, err := os.ReadFile(storeConfig.Path)
+	if err != nil {
+		t.Fatalf("reading token store: %v", err)
+	}
+	if strings.Contains(string(data), "refresh-0") {
+		t.Fatal("refresh token was stored in plaintext")
+	}
+
+	manager := NewManager(store)
+	token, err := manager.AccessToken(context.Background(), &account)
+	if err != nil {
+		t.Fatalf("AccessToken: %v", err)
+	}
+	if token != "access-refresh-0" || fake.refreshes != 1 {
+		t.Errorf("expected one refresh yielding access-refresh-0, got %q after %d refreshes", token, fake.refreshes)
+	}
+
+	if _, err := manager.AccessToken(context.Background(), &account); err != nil || fake.refreshes != 1 {
+		t.Errorf("expected the cached access token to be reused, got %v after %d refreshes", err, fake.refreshes)
+	}
+
+	reopened, err := OpenStore(storeConfig)
+	if err != nil {
+		t.Fatalf("reopening store: %v", err)
+	}
+	if rt, err := reopened.RefreshToken(account.Username); err != nil || rt != "refresh-rotated" {
+		t.Errorf("expected the rotated refresh token to be persisted, got %q, %v", rt, err)
+	}
+}
+
+func TestStoreBindsTokensToAccounts(t *testing.T) {
+	dir := t.TempDir()
+	config := types.TokenStoreConfig{Path: filepath.Join(dir, "tokens.json"), KeyPath: filepath.Join(dir, "token.key")}
+
+	store, err := OpenStore(config)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	for _, account := range []string{"alice@example.com", "mallory@example.com"} {
+		if err := store.SaveRefreshToken(account, "token-of-"+account); err != nil {
+			t.Fatalf("SaveRefreshToken: %v", err)
+		}
+	}
+
+	// Copy mallory's ciphertext over alice's entry in the file
+	data, err := os.ReadFile(config.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens map[string]storedToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	tokens["alice@example.com"] = tokens["mallory@example.com"]
+	if data, err = json.Marshal(tokens); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.Path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if rt, err := store.RefreshToken("alice@example.com"); err == nil {
+		t.Errorf("expected a token swapped in from another account to fail, got %q", rt)
+	}
+}
+
+func TestStoreRejectsWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	config := types.TokenStoreConfig{Path: filepath.Join(dir, "tokens.json"), KeyPath: filepath.Join(dir, "a.key")}
+
+	store, err := OpenStore(config)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	if err := store.SaveRefreshToken("agent@example.com", "secret"); err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+
+	config.KeyPath = filepath.Join(dir, "b.key")
+	other, err := OpenStore(config)
+	if err != nil {
+		t.Fatalf("OpenStore with new key: %v", err)
+	}
+	if _, err := other.RefreshToken("agent@example.com"); err == nil {
+		t.Error("expected decryption with a different key to fail")
+	}
+}
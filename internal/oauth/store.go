@@ -0,0 +1,211 @@
+package oauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+	"ai-presence-mcp/pkg/utils"
+)
+
+// KeyEnv names the environment variable that may hold the base64 encoded
+// 32-byte token encryption key instead of a key file
+const KeyEnv = "SAPPHIREDUCK_TOKEN_KEY"
+
+// storedToken is the on-disk form of an account's tokens. Only the refresh
+// token is persisted, encrypted with AES-256-GCM and bound to the account, so
+// tokens swapped between accounts in the file fail to decrypt.
+type storedToken struct {
+	RefreshToken string    `json:"refresh_token"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Store keeps encrypted OAuth refresh tokens keyed by account
+type Store struct {
+	path string
+	aead cipher.AEAD
+
+	mu     sync.Mutex
+	tokens map[string]storedToken
+}
+
+// OpenStore opens the token file at config.Path. The encryption key is read
+// from $SAPPHIREDUCK_TOKEN_KEY or config.KeyPath, which is created with a
+// random key on first use.
+func OpenStore(config types.TokenStoreConfig) (*Store, error) {
+	key, err := loadKey(config.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise token encryption: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise token encryption: %w", err)
+	}
+
+	s := &Store{
+		path:   config.Path,
+		aead:   aead,
+		tokens: make(map[string]storedToken),
+	}
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadLocked rereads the token file, which the -oauth-login command may have
+// updated while the server is running. s.mu must be held.
+func (s *Store) loadLocked() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read token store: %w", err)
+	}
+
+	tokens := make(map[string]storedToken)
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return fmt.Errorf("failed to parse token store %s: %w", s.path, err)
+		}
+	}
+	s.tokens = tokens
+	return nil
+}
+
+// loadKey returns the token encryption key, generating a key file if needed
+func loadKey(path string) ([]byte, error) {
+	if encoded := os.Getenv(KeyEnv); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s must be a base64 encoded 32-byte key", KeyEnv)
+		}
+		return key, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("token key file %s does not hold a base64 encoded 32-byte key", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read token key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate token key: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create token key directory: %w", err)
+		}
+	}
+	// O_EXCL so two processes starting together cannot overwrite each other's key
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return loadKey(path)
+		}
+		return nil, fmt.Errorf("failed to create token key: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("failed to write token key: %w", err)
+	}
+	return key, nil
+}
+
+// RefreshToken returns the decrypted refresh token stored for account
+func (s *Store) RefreshToken(account string) (string, error) {
+	s.mu.Lock()
+	err := s.loadLocked()
+	stored, ok := s.tokens[account]
+	s.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("no OAuth token stored for %s, run -oauth-login %s first", account, account)
+	}
+	return s.decrypt(account, stored.RefreshToken)
+}
+
+// SaveRefreshToken encrypts and stores the refresh token for account
+func (s *Store) SaveRefreshToken(account, refreshToken string) error {
+	sealed, err := s.encrypt(account, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	s.tokens[account] = storedToken{RefreshToken: sealed, UpdatedAt: time.Now().UTC()}
+	return s.saveLocked()
+}
+
+// Delete forgets the tokens stored for account
+func (s *Store) Delete(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	delete(s.tokens, account)
+	return s.saveLocked()
+}
+
+// encrypt seals plaintext with account as the associated data
+func (s *Store) encrypt(account, plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt token: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plaintext), []byte(account))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a token sealed by encrypt for account
+func (s *Store) decrypt(account, encoded string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return "", fmt.Errorf("stored OAuth token is corrupt")
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(account))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt stored OAuth token for %s (wrong key, or not stored for this account?): %w", account, err)
+	}
+	return string(plaintext), nil
+}
+
+// saveLocked atomically writes the token file. s.mu must be held.
+func (s *Store) saveLocked() error {
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode token store: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	return nil
+}
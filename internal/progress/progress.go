@@ -0,0 +1,24 @@
+// Package progress carries progress reporting through a context, so that
+// long-running operations can tell whoever started them how far they got
+// without knowing who that is
+package progress
+
+import "context"
+
+// Reporter receives progress updates. progress grows with every update;
+// total is 0 when it is unknown.
+type Reporter func(progress, total float64, message string)
+
+type reporterKey struct{}
+
+// WithReporter returns a context whose operations report to reporter
+func WithReporter(ctx context.Context, reporter Reporter) context.Context {
+	return context.WithValue(ctx, reporterKey{}, reporter)
+}
+
+// Report sends an update to the context's reporter, if it has one
+func Report(ctx context.Context, progress, total float64, message string) {
+	if reporter, ok := ctx.Value(reporterKey{}).(Reporter); ok && reporter != nil {
+		reporter(progress, total, message)
+	}
+}
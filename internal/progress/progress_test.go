@@ -0,0 +1,24 @@
+package progress
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	// Without a reporter updates go nowhere
+	Report(context.Background(), 1, 2, "ignored")
+
+	var got []float64
+	ctx := WithReporter(context.Background(), func(progress, total float64, message string) {
+		if total != 2 || message != "step" {
+			t.Errorf("unexpected update %v/%v %q", progress, total, message)
+		}
+		got = append(got, progress)
+	})
+	Report(ctx, 1, 2, "step")
+	Report(ctx, 2, 2, "step")
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("expected updates 1 and 2, got %v", got)
+	}
+}
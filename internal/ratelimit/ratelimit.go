@@ -0,0 +1,197 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/pkg/utils"
+)
+
+// Store holds token buckets and rolling event windows by key and persists
+// them to a JSON file so limits survive restarts
+type Store struct {
+	path string
+	now  func() time.Time
+
+	mu    sync.Mutex
+	state storeState
+}
+
+type storeState struct {
+	Buckets map[string]*bucket     `json:"buckets"`
+	Windows map[string][]time.Time `json:"windows"`
+}
+
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// Open loads the store at path. An empty path keeps state in memory only.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		now:  time.Now,
+		state: storeState{
+			Buckets: make(map[string]*bucket),
+			Windows: make(map[string][]time.Time),
+		},
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit state: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.state); err != nil {
+			return nil, fmt.Errorf("failed to parse rate limit state %s: %w", path, err)
+		}
+	}
+	if s.state.Buckets == nil {
+		s.state.Buckets = make(map[string]*bucket)
+	}
+	if s.state.Windows == nil {
+		s.state.Windows = make(map[string][]time.Time)
+	}
+
+	return s, nil
+}
+
+// Take removes one token from the bucket for key, which refills at
+// perMinute tokens per minute up to burst. If the bucket is empty nothing
+// is taken and the time until a token is available is returned.
+func (s *Store) Take(key string, perMinute float64, burst int) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.refillLocked(key, perMinute, burst)
+	if b.Tokens < 1 {
+		missing := 1 - b.Tokens
+		return time.Duration(missing / perMinute * float64(time.Minute)), nil
+	}
+
+	b.Tokens--
+	return 0, s.saveLocked()
+}
+
+// Tokens returns the number of whole tokens currently available for key
+func (s *Store) Tokens(key string, perMinute float64, burst int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int(math.Floor(s.refillLocked(key, perMinute, burst).Tokens))
+}
+
+// Count returns the number of events recorded for key within window
+func (s *Store) Count(key string, window time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pruneLocked(key, window))
+}
+
+// ResetIn returns how long until the oldest event for key leaves window,
+// freeing up one unit of quota
+func (s *Store) ResetIn(key string, window time.Duration) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := s.pruneLocked(key, window)
+	if len(events) == 0 {
+		return 0
+	}
+	return events[0].Add(window).Sub(s.now())
+}
+
+// Record adds an event for each key, pruning events older than window
+func (s *Store) Record(window time.Duration, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC()
+	for _, key := range keys {
+		s.state.Windows[key] = append(s.pruneLocked(key, window), now)
+	}
+	return s.saveLocked()
+}
+
+// Release removes the most recent event of each key, undoing a Record whose
+// event did not happen after all
+func (s *Store) Release(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		events := s.state.Windows[key]
+		switch len(events) {
+		case 0:
+		case 1:
+			delete(s.state.Windows, key)
+		default:
+			s.state.Windows[key] = events[:len(events)-1]
+		}
+	}
+	return s.saveLocked()
+}
+
+func (s *Store) refillLocked(key string, perMinute float64, burst int) *bucket {
+	now := s.now()
+	b, ok := s.state.Buckets[key]
+	if !ok {
+		b = &bucket{Tokens: float64(burst), Updated: now}
+		s.state.Buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.Updated).Minutes()
+	if elapsed > 0 {
+		b.Tokens = math.Min(float64(burst), b.Tokens+elapsed*perMinute)
+		b.Updated = now
+	}
+	return b
+}
+
+func (s *Store) pruneLocked(key string, window time.Duration) []time.Time {
+	cutoff := s.now().Add(-window)
+	events := s.state.Windows[key]
+
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	events = events[i:]
+
+	if len(events) == 0 {
+		delete(s.state.Windows, key)
+	} else {
+		s.state.Windows[key] = events
+	}
+	return events
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return fmt.Errorf("failed to encode rate limit state: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to write rate limit state: %w", err)
+	}
+	return nil
+}
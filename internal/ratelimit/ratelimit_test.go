@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func openWithClock(t *testing.T, path string, clock *fakeClock) *Store {
+	t.Helper()
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	s.now = clock.now
+	return s
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)}
+	s := openWithClock(t, "", clock)
+
+	for i := 0; i < 2; i++ {
+		if wait, _ := s.Take("k", 6, 2); wait != 0 {
+			t.Fatalf("take %d: expected a token, wait %s", i, wait)
+		}
+	}
+
+	wait, _ := s.Take("k", 6, 2)
+	if wait != 10*time.Second {
+		t.Fatalf("expected to wait 10s for the next token, got %s", wait)
+	}
+
+	clock.advance(10 * time.Second)
+	if wait, _ := s.Take("k", 6, 2); wait != 0 {
+		t.Fatalf("expected a refilled token, wait %s", wait)
+	}
+
+	clock.advance(time.Hour)
+	if got := s.Tokens("k", 6, 2); got != 2 {
+		t.Errorf("expected the bucket to be capped at its burst, got %d", got)
+	}
+}
+
+func TestRollingWindowPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	clock := &fakeClock{t: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)}
+
+	s := openWithClock(t, path, clock)
+	if err := s.Record(24*time.Hour, "send:a", "send:a:example.com"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	clock.advance(6 * time.Hour)
+	if err := s.Record(24*time.Hour, "send:a"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	reopened := openWithClock(t, path, clock)
+	if got := reopened.Count("send:a", 24*time.Hour); got != 2 {
+		t.Fatalf("expected 2 events after reopening, got %d", got)
+	}
+	if got := reopened.ResetIn("send:a", 24*time.Hour); got != 18*time.Hour {
+		t.Errorf("expected the oldest event to expire in 18h, got %s", got)
+	}
+
+	clock.advance(18 * time.Hour)
+	if got := reopened.Count("send:a", 24*time.Hour); got != 1 {
+		t.Errorf("expected the oldest event to fall out of the window, got %d", got)
+	}
+	if got := reopened.Count("send:a:example.com", 24*time.Hour); got != 0 {
+		t.Errorf("expected the domain event to expire, got %d", got)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)}
+	s := openWithClock(t, "", clock)
+
+	s.Record(24*time.Hour, "send:a")
+	clock.advance(time.Hour)
+	s.Record(24*time.Hour, "send:a", "send:a:example.com")
+
+	if err := s.Release("send:a", "send:a:example.com"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if got := s.Count("send:a", 24*time.Hour); got != 1 {
+		t.Errorf("expected one event left, got %d", got)
+	}
+	if got := s.ResetIn("send:a", 24*time.Hour); got != 23*time.Hour {
+		t.Errorf("expected the older event to be kept, got reset in %s", got)
+	}
+	if got := s.Count("send:a:example.com", 24*time.Hour); got != 0 {
+		t.Errorf("expected the domain event to be released, got %d", got)
+	}
+}
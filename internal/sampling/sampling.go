@@ -0,0 +1,97 @@
+// Package sampling carries access to the calling client's model through a
+// context, so that tools can ask it for completions without knowing how the
+// client is reached, and helps fit prompts into a token budget
+package sampling
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrUnsupported is returned by Sample when the client cannot sample
+var ErrUnsupported = errors.New("the client does not support sampling")
+
+// charsPerToken is the rough number of characters in a token of English
+// text, which is all a budget needs
+const charsPerToken = 4
+
+// truncatedMarker ends text shortened to fit a budget
+const truncatedMarker = " [...]"
+
+// Request asks the client's model to complete Prompt
+type Request struct {
+	SystemPrompt string
+	Prompt       string
+	// MaxTokens bounds the length of the completion
+	MaxTokens int
+}
+
+// Sampler completes requests with the client's model
+type Sampler func(ctx context.Context, req Request) (string, error)
+
+type samplerKey struct{}
+
+// WithSampler returns a context whose operations sample with sampler
+func WithSampler(ctx context.Context, sampler Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, sampler)
+}
+
+// Sample completes req with the context's sampler, or returns
+// ErrUnsupported if it has none
+func Sample(ctx context.Context, req Request) (string, error) {
+	sampler, ok := ctx.Value(samplerKey{}).(Sampler)
+	if !ok || sampler == nil {
+		return "", ErrUnsupported
+	}
+	return sampler(ctx, req)
+}
+
+// EstimateTokens returns roughly how many tokens s takes up
+func EstimateTokens(s string) int {
+	return (len([]rune(s)) + charsPerToken - 1) / charsPerToken
+}
+
+// Truncate shortens s to about tokens, cutting at a word boundary and
+// marking the cut
+func Truncate(s string, tokens int) string {
+	if EstimateTokens(s) <= tokens {
+		return s
+	}
+	runes := []rune(s)
+	limit := tokens*charsPerToken - len(truncatedMarker)
+	if limit <= 0 {
+		return strings.TrimSpace(truncatedMarker)
+	}
+	cut := limit
+	for cut > limit/2 && !unicode.IsSpace(runes[cut]) {
+		cut--
+	}
+	if cut == limit/2 {
+		cut = limit
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + truncatedMarker
+}
+
+// Fit shortens texts so that together they take up about tokens. Short
+// texts are kept whole and the long ones share what remains equally.
+func Fit(texts []string, tokens int) []string {
+	order := make([]int, len(texts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return EstimateTokens(texts[order[a]]) < EstimateTokens(texts[order[b]])
+	})
+
+	fitted := make([]string, len(texts))
+	remaining := tokens
+	for i, idx := range order {
+		share := remaining / (len(order) - i)
+		fitted[idx] = Truncate(texts[idx], share)
+		remaining -= EstimateTokens(fitted[idx])
+	}
+	return fitted
+}
@@ -0,0 +1,57 @@
+package sampling
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	if _, err := Sample(context.Background(), Request{Prompt: "hi"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported without a sampler, got %v", err)
+	}
+
+	ctx := WithSampler(context.Background(), func(_ context.Context, req Request) (string, error) {
+		return "echo: " + req.Prompt, nil
+	})
+	if got, err := Sample(ctx, Request{Prompt: "hi"}); err != nil || got != "echo: hi" {
+		t.Errorf("Sample = %q, %v", got, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("short text", 10); got != "short text" {
+		t.Errorf("text within the budget was changed to %q", got)
+	}
+
+	long := strings.Repeat("word ", 100)
+	got := Truncate(long, 10)
+	if !strings.HasSuffix(got, truncatedMarker) || EstimateTokens(got) > 10 {
+		t.Errorf("Truncate to 10 tokens = %q (%d tokens)", got, EstimateTokens(got))
+	}
+	if strings.Contains(got, "wor ") || strings.Contains(got, "wo ") {
+		t.Errorf("expected a cut at a word boundary, got %q", got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	short := "a short note"
+	long := strings.Repeat("x", 4000)
+	fitted := Fit([]string{long, short, long}, 200)
+
+	if fitted[1] != short {
+		t.Errorf("short text was changed to %q", fitted[1])
+	}
+	total := 0
+	for _, f := range fitted {
+		total += EstimateTokens(f)
+	}
+	if total > 200 {
+		t.Errorf("fitted texts take %d tokens, want at most 200", total)
+	}
+	if EstimateTokens(fitted[0]) < 90 || EstimateTokens(fitted[2]) < 90 {
+		t.Errorf("expected the long texts to share the rest, got %d and %d tokens",
+			EstimateTokens(fitted[0]), EstimateTokens(fitted[2]))
+	}
+}
@@ -0,0 +1,126 @@
+// Package secrets resolves secret references used in place of plaintext
+// values in the configuration file
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execTimeout bounds how long an exec: reference may run, e.g. while a
+// password manager waits to be unlocked
+const execTimeout = 30 * time.Second
+
+// LiteralPrefix marks a value that is used as is, even if the rest of it
+// looks like a reference, e.g. "literal:file:not-a-path"
+const LiteralPrefix = "literal:"
+
+// IsReference reports whether value is a secret reference rather than a
+// literal: ${ENV_VAR}, file:/path or exec:command
+func IsReference(value string) bool {
+	_, ok := envName(value)
+	return ok || strings.HasPrefix(value, "file:") || strings.HasPrefix(value, "exec:")
+}
+
+// Literal returns the value a literal: escape stands for
+func Literal(value string) (string, bool) {
+	return strings.CutPrefix(value, LiteralPrefix)
+}
+
+// Ambiguous reports whether a value used as a literal could be mistaken for
+// a reference: one starting with $, or with file:, exec: or literal: in
+// another case
+func Ambiguous(value string) bool {
+	if IsReference(value) || strings.HasPrefix(value, LiteralPrefix) {
+		return false
+	}
+	if strings.HasPrefix(value, "$") {
+		return true
+	}
+	lower := strings.ToLower(value)
+	for _, prefix := range []string{"file:", "exec:", LiteralPrefix} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the secret a reference points to, the escaped value of a
+// literal: value, or value itself if it is not a reference. Errors never
+// include the secret.
+func Resolve(value string) (string, error) {
+	if literal, ok := Literal(value); ok {
+		return literal, nil
+	}
+
+	if name, ok := envName(value); ok {
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	}
+
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return trimNewline(string(data)), nil
+	}
+
+	if command, ok := strings.CutPrefix(value, "exec:"); ok {
+		return run(command)
+	}
+
+	return value, nil
+}
+
+// envName extracts NAME from a ${NAME} reference
+func envName(value string) (string, bool) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return "", false
+	}
+	name := value[2 : len(value)-1]
+	return name, name != ""
+}
+
+// run executes command through the shell and returns its first line of output
+func run(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Stdin = nil
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("secret command timed out after %s", execTimeout)
+		}
+		// stderr is reported as it may explain the failure; stdout could hold the secret
+		return "", fmt.Errorf("secret command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Password managers such as pass print the secret on the first line
+	secret, _, _ := strings.Cut(stdout.String(), "\n")
+	secret = strings.TrimSuffix(secret, "\r")
+	if secret == "" {
+		return "", errors.New("secret command printed nothing")
+	}
+	return secret, nil
+}
+
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SAPPHIREDUCK_TEST_SECRET", "from-env")
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	tests := []struct {
+		value, want string
+		reference   bool
+	}{
+		{"plain-password", "plain-password", false},
+		{"${SAPPHIREDUCK_TEST_SECRET}", "from-env", true},
+		{"file:" + file, "from-file", true},
+		{"exec:printf 'from-exec\\nmetadata: x\\n'", "from-exec", true},
+		{"$NOT_A_REFERENCE", "$NOT_A_REFERENCE", false},
+		{"literal:file:not-a-path", "file:not-a-path", false},
+		{"literal:${SAPPHIREDUCK_TEST_SECRET}", "${SAPPHIREDUCK_TEST_SECRET}", false},
+	}
+	for _, tt := range tests {
+		if got := IsReference(tt.value); got != tt.reference {
+			t.Errorf("IsReference(%q) = %v, want %v", tt.value, got, tt.reference)
+		}
+		got, err := Resolve(tt.value)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAmbiguous(t *testing.T) {
+	tests := map[string]bool{
+		"plain-password":        false,
+		"${SECRET}":             false,
+		"file:/run/secret":      false,
+		"literal:$dollar":       false,
+		"$dollar":               true,
+		"${unterminated":        true,
+		"File:/run/secret":      true,
+		"EXEC:pass show mail":   true,
+		"Literal:not-an-escape": true,
+	}
+	for value, want := range tests {
+		if got := Ambiguous(value); got != want {
+			t.Errorf("Ambiguous(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestResolveErrorsDoNotLeakSecrets(t *testing.T) {
+	if _, err := Resolve("${SAPPHIREDUCK_TEST_UNSET}"); err == nil {
+		t.Error("expected an unset variable to fail")
+	}
+
+	_, err := Resolve("exec:echo hunter2; echo oops >&2; exit 3")
+	if err == nil {
+		t.Fatal("expected a failing command to fail")
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error leaks command output: %v", err)
+	}
+	if !strings.Contains(err.Error(), "oops") {
+		t.Errorf("expected stderr in the error, got %v", err)
+	}
+}
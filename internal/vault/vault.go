@@ -0,0 +1,379 @@
+// Package vault keeps email account credentials encrypted at rest so no
+// secret has to be readable on disk
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-presence-mcp/pkg/types"
+	"ai-presence-mcp/pkg/utils"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv names the environment variable holding the vault passphrase
+const PassphraseEnv = "SAPPHIREDUCK_VAULT_PASSPHRASE"
+
+// Prefix marks a configuration value as a reference to a vault credential
+const Prefix = "vault:"
+
+// checkValue is sealed into every vault so a wrong key is detected when the
+// vault is opened rather than on first use
+const checkValue = "sapphireduck-vault"
+
+// scrypt parameters for new vaults, as recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrNotFound is returned for a credential the vault does not hold
+var ErrNotFound = errors.New("credential not found in vault")
+
+// kdfParams records how a passphrase vault derives its key
+type kdfParams struct {
+	Name string `json:"name"`
+	Salt string `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+// credential is one sealed secret
+type credential struct {
+	Secret    string    `json:"secret"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// file is the on-disk form of the vault
+type file struct {
+	Version int `json:"version"`
+	// KDF is nil for vaults keyed by a key file
+	KDF         *kdfParams            `json:"kdf,omitempty"`
+	Check       string                `json:"check"`
+	Credentials map[string]credential `json:"credentials"`
+}
+
+// Entry describes a stored credential without revealing it
+type Entry struct {
+	Name      string
+	UpdatedAt time.Time
+}
+
+// Vault stores credentials sealed with AES-256-GCM. Each secret is bound to
+// its name, so sealed values cannot be swapped between accounts.
+type Vault struct {
+	path string
+	aead cipher.AEAD
+	kdf  *kdfParams
+
+	mu    sync.Mutex
+	creds map[string]credential
+}
+
+// IsReference reports whether value refers to a vault credential
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Open unlocks the vault at config.Path, creating it on first save. The key
+// is read from config.KeyPath if set, which is created with a random key on
+// first use, and is otherwise derived from $SAPPHIREDUCK_VAULT_PASSPHRASE.
+func Open(config types.VaultConfig) (*Vault, error) {
+	stored, err := readFile(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	kdf := stored.KDF
+	if config.KeyPath != "" {
+		if kdf != nil {
+			return nil, fmt.Errorf("vault %s is protected by a passphrase, unset vault.key_path", config.Path)
+		}
+		if key, err = loadKey(config.KeyPath); err != nil {
+			return nil, err
+		}
+	} else {
+		passphrase := os.Getenv(PassphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("set %s or vault.key_path to unlock the vault", PassphraseEnv)
+		}
+		if stored.Check != "" && kdf == nil {
+			return nil, fmt.Errorf("vault %s is protected by a key file, set vault.key_path", config.Path)
+		}
+		if kdf == nil {
+			if kdf, err = newKDF(); err != nil {
+				return nil, err
+			}
+		}
+		if key, err = deriveKey(passphrase, kdf); err != nil {
+			return nil, err
+		}
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{path: config.Path, aead: aead, kdf: kdf, creds: stored.Credentials}
+	if stored.Check != "" {
+		if _, err := v.open(stored.Check, "check"); err != nil {
+			return nil, fmt.Errorf("failed to unlock vault %s (wrong passphrase or key?)", config.Path)
+		}
+	}
+	return v, nil
+}
+
+// readFile loads the vault file, returning an empty vault if it does not exist
+func readFile(path string) (*file, error) {
+	stored := &file{Credentials: make(map[string]credential)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return stored, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+	if err := json.Unmarshal(data, stored); err != nil {
+		return nil, fmt.Errorf("failed to parse vault %s: %w", path, err)
+	}
+	if stored.Version != 1 {
+		return nil, fmt.Errorf("vault %s has unsupported version %d", path, stored.Version)
+	}
+	if stored.Credentials == nil {
+		stored.Credentials = make(map[string]credential)
+	}
+	return stored, nil
+}
+
+func newKDF() (*kdfParams, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate vault salt: %w", err)
+	}
+	return &kdfParams{
+		Name: "scrypt",
+		Salt: base64.StdEncoding.EncodeToString(salt),
+		N:    scryptN,
+		R:    scryptR,
+		P:    scryptP,
+	}, nil
+}
+
+func deriveKey(passphrase string, kdf *kdfParams) ([]byte, error) {
+	if kdf.Name != "scrypt" {
+		return nil, fmt.Errorf("unsupported vault key derivation %q", kdf.Name)
+	}
+	salt, err := base64.StdEncoding.DecodeString(kdf.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("vault salt is corrupt")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, kdf.N, kdf.R, kdf.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %w", err)
+	}
+	return key, nil
+}
+
+// loadKey returns the vault key from a key file, generating one if needed
+func loadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("vault key file %s does not hold a base64 encoded 32-byte key", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read vault key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate vault key: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create vault key directory: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return loadKey(path)
+		}
+		return nil, fmt.Errorf("failed to create vault key: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("failed to write vault key: %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise vault encryption: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise vault encryption: %w", err)
+	}
+	return aead, nil
+}
+
+// reloadLocked rereads the vault file, which the CLI may have changed while
+// the server is running. v.mu must be held.
+func (v *Vault) reloadLocked() error {
+	stored, err := readFile(v.path)
+	if err != nil {
+		return err
+	}
+	v.creds = stored.Credentials
+	return nil
+}
+
+// Get returns the decrypted credential stored under name
+func (v *Vault) Get(name string) (string, error) {
+	v.mu.Lock()
+	err := v.reloadLocked()
+	cred, ok := v.creds[name]
+	v.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return v.open(cred.Secret, name)
+}
+
+// List returns the stored credentials sorted by name
+func (v *Vault) List() ([]Entry, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.reloadLocked(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(v.creds))
+	for name, cred := range v.creds {
+		entries = append(entries, Entry{Name: name, UpdatedAt: cred.UpdatedAt})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Add stores a new credential, failing if name is already taken
+func (v *Vault) Add(name, secret string) error {
+	return v.put(name, secret, false)
+}
+
+// Rotate replaces an existing credential
+func (v *Vault) Rotate(name, secret string) error {
+	return v.put(name, secret, true)
+}
+
+func (v *Vault) put(name, secret string, replace bool) error {
+	if name == "" {
+		return errors.New("credential name is required")
+	}
+	if secret == "" {
+		return errors.New("credential must not be empty")
+	}
+	sealed, err := v.seal(secret, name)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.reloadLocked(); err != nil {
+		return err
+	}
+	_, exists := v.creds[name]
+	if replace && !exists {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if !replace && exists {
+		return fmt.Errorf("vault already holds a credential for %s, rotate it instead", name)
+	}
+	v.creds[name] = credential{Secret: sealed, UpdatedAt: time.Now().UTC()}
+	return v.saveLocked()
+}
+
+// Remove deletes the credential stored under name
+func (v *Vault) Remove(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.reloadLocked(); err != nil {
+		return err
+	}
+	if _, ok := v.creds[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(v.creds, name)
+	return v.saveLocked()
+}
+
+func (v *Vault) seal(plaintext, name string) (string, error) {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt credential: %w", err)
+	}
+	sealed := v.aead.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (v *Vault) open(encoded, name string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < v.aead.NonceSize() {
+		return "", fmt.Errorf("vault credential %s is corrupt", name)
+	}
+	nonce, ciphertext := sealed[:v.aead.NonceSize()], sealed[v.aead.NonceSize():]
+	plaintext, err := v.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt vault credential %s: %w", name, err)
+	}
+	return string(plaintext), nil
+}
+
+// saveLocked atomically writes the vault file. v.mu must be held.
+func (v *Vault) saveLocked() error {
+	check, err := v.seal(checkValue, "check")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file{
+		Version:     1,
+		KDF:         v.kdf,
+		Check:       check,
+		Credentials: v.creds,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(v.path, data); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-presence-mcp/pkg/types"
+)
+
+func TestPassphraseVault(t *testing.T) {
+	config := types.VaultConfig{Path: filepath.Join(t.TempDir(), "vault.json")}
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	v, err := Open(config)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := v.Add("agent@example.com", "app-password"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := v.Add("agent@example.com", "other"); err == nil {
+		t.Error("expected adding an existing credential to fail")
+	}
+	if err := v.Rotate("missing@example.com", "x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected rotating a missing credential to fail with ErrNotFound, got %v", err)
+	}
+
+	data, err := os.ReadFile(config.Path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.Contains(string(data), "app-password") {
+		t.Fatal("vault file holds the credential in plaintext")
+	}
+
+	// A second process sees the credential and can rotate it
+	other, err := Open(config)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := other.Rotate("agent@example.com", "new-password"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if got, err := v.Get("agent@example.com"); err != nil || got != "new-password" {
+		t.Errorf("Get = %q, %v; want the rotated credential", got, err)
+	}
+
+	entries, err := v.List()
+	if err != nil || len(entries) != 1 || entries[0].Name != "agent@example.com" {
+		t.Errorf("List = %+v, %v", entries, err)
+	}
+	if err := v.Remove("agent@example.com"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := v.Get("agent@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a removed credential to be gone, got %v", err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := Open(config); err == nil {
+		t.Error("expected a wrong passphrase to be rejected")
+	}
+}
+
+func TestKeyFileVault(t *testing.T) {
+	dir := t.TempDir()
+	config := types.VaultConfig{
+		Path:    filepath.Join(dir, "vault.json"),
+		KeyPath: filepath.Join(dir, "vault.key"),
+	}
+
+	v, err := Open(config)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := v.Add("a", "secret-a"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := v.Add("b", "secret-b"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Swapping sealed values between names must not decrypt
+	v.mu.Lock()
+	v.creds["a"], v.creds["b"] = v.creds["b"], v.creds["a"]
+	if err := v.saveLocked(); err != nil {
+		t.Fatalf("saveLocked: %v", err)
+	}
+	v.mu.Unlock()
+	if _, err := v.Get("a"); err == nil {
+		t.Error("expected a credential moved to another name to fail authentication")
+	}
+
+	t.Setenv(PassphraseEnv, "passphrase")
+	if _, err := Open(types.VaultConfig{Path: config.Path}); err == nil {
+		t.Error("expected a key file vault to refuse a passphrase")
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strings"
 
 	"ai-presence-mcp/cmd/server"
+	"ai-presence-mcp/internal/mcp"
 )
 
 func main() {
@@ -13,10 +15,76 @@ func main() {
 	log.SetOutput(os.Stderr)
 
 	testMode := flag.Bool("test", false, "Run in test mode to verify MCP server functionality")
+	transport := flag.String("transport", mcp.TransportStdio, "Transport to serve MCP over: "+strings.Join(mcp.Transports, ", "))
+	checkConfig := flag.Bool("check-config", false, "Validate the configuration file and exit")
+	listApprovals := flag.Bool("approvals", false, "List actions awaiting human approval and exit")
+	approveToken := flag.String("approve", "", "Approve the pending action with this token and exit")
+	denyToken := flag.String("deny", "", "Deny the pending action with this token and exit")
+	oauthLogin := flag.String("oauth-login", "", "Authorize this account with its OAuth provider and exit")
+	discover := flag.String("discover", "", "Discover the mail servers of this email address, print them as config and exit")
+	vaultList := flag.Bool("vault-list", false, "List the credentials in the vault and exit")
+	vaultAdd := flag.String("vault-add", "", "Store a credential read from stdin in the vault under this name and exit")
+	vaultRotate := flag.String("vault-rotate", "", "Replace the vault credential with this name, reading it from stdin, and exit")
+	vaultRemove := flag.String("vault-remove", "", "Remove the vault credential with this name and exit")
 	flag.Parse()
 
-	if err := server.Run(*testMode); err != nil {
+	if *checkConfig {
+		if err := server.CheckConfig(); err != nil {
+			log.Printf("Config error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *listApprovals || *approveToken != "" || *denyToken != "" {
+		if err := server.RunApprovalCommand(*listApprovals, *approveToken, *denyToken); err != nil {
+			log.Printf("Approval error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *oauthLogin != "" {
+		if err := server.RunOAuthLogin(*oauthLogin); err != nil {
+			log.Printf("OAuth login error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *discover != "" {
+		if err := server.RunDiscover(*discover); err != nil {
+			log.Printf("Discovery error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if action, name := vaultCommand(*vaultList, *vaultAdd, *vaultRotate, *vaultRemove); action != "" {
+		if err := server.RunVaultCommand(action, name); err != nil {
+			log.Printf("Vault error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := server.Run(*testMode, *transport); err != nil {
 		log.Printf("Server error: %v", err)
 		os.Exit(1)
 	}
 }
+
+// vaultCommand maps the vault flags to a subcommand and credential name
+func vaultCommand(list bool, add, rotate, remove string) (action, name string) {
+	switch {
+	case add != "":
+		return server.VaultAdd, add
+	case rotate != "":
+		return server.VaultRotate, rotate
+	case remove != "":
+		return server.VaultRemove, remove
+	case list:
+		return server.VaultList, ""
+	}
+	return "", ""
+}
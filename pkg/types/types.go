@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // MCP Protocol Types
 
 type MCPMessage struct {
@@ -68,9 +70,58 @@ type ToolContent struct {
 	Text string `json:"text"`
 }
 
+// PromptArgument describes an argument a prompt accepts
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt. It carries either Text
+// or an embedded Resource.
+type PromptMessage struct {
+	Role     string           `json:"role"`
+	Text     string           `json:"text,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
+}
+
+// PromptResult is a prompt rendered with its arguments
+type PromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// Resource describes a concrete resource offered in resources/list
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContent is one representation of a resource. Exactly one of
+// Text and Blob is set.
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     []byte `json:"blob,omitempty"`
+}
+
 // Email Types
 
 type EmailConfig struct {
+	// Name is a stable short name the account can be selected by instead
+	// of its username
+	Name         string `yaml:"name"`
+	Description  string `yaml:"description"`
+	// Default marks the account used when none is requested; without one
+	// the first account is the default
+	Default      bool   `yaml:"default"`
+	// ReadOnly opens mailboxes read-only and forbids sending; NoSend only
+	// forbids sending
+	ReadOnly     bool   `yaml:"read_only"`
+	NoSend       bool   `yaml:"no_send"`
 	Provider     string `yaml:"provider"`
 	Username     string `yaml:"username"`
 	Password     string `yaml:"password"`
@@ -78,7 +129,144 @@ type EmailConfig struct {
 	IMAPPort     int    `yaml:"imap_port"`
 	SMTPServer   string `yaml:"smtp_server"`
 	SMTPPort     int    `yaml:"smtp_port"`
-	UseTLS       bool   `yaml:"use_tls"`
+	UseTLS       *bool  `yaml:"use_tls"`
+	TLS          TLSConfig       `yaml:"tls"`
+	// Auth selects the IMAP/SMTP mechanism: plain, login, cram-md5,
+	// scram-sha-1, scram-sha-256, xoauth2 or oauthbearer. OAuth mechanisms
+	// use tokens from the OAuth block, or Password as a static access token.
+	Auth         string          `yaml:"auth"`
+	Policy       RecipientPolicy `yaml:"policy"`
+	Limits       AccountLimits   `yaml:"limits"`
+	DKIM         *DKIMConfig     `yaml:"dkim,omitempty"`
+	Identities   []Identity      `yaml:"identities"`
+	OAuth        *OAuthConfig    `yaml:"oauth,omitempty"`
+}
+
+// TLSConfig controls how an account's IMAP and SMTP connections are secured.
+// Mode is "implicit", "starttls" or "none"; IMAPMode and SMTPMode override it
+// per protocol. Without a mode, use_tls: false means "none" and otherwise the
+// port picks implicit TLS (993, 465) or STARTTLS.
+type TLSConfig struct {
+	Mode       string `yaml:"mode"`
+	IMAPMode   string `yaml:"imap_mode"`
+	SMTPMode   string `yaml:"smtp_mode"`
+	CAFile     string `yaml:"ca_file"`
+	ServerName string `yaml:"server_name"`
+	MinVersion string `yaml:"min_version"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+}
+
+// OAuthConfig configures OAuth2 for an account using xoauth2 or oauthbearer
+// auth. A provider preset ("google" or "microsoft") supplies the endpoints
+// and scopes; AuthURL, TokenURL and Scopes override it.
+type OAuthConfig struct {
+	Provider     string   `yaml:"provider"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	AuthURL      string   `yaml:"auth_url"`
+	TokenURL     string   `yaml:"token_url"`
+	Scopes       []string `yaml:"scopes"`
+	RedirectPort int      `yaml:"redirect_port"`
+}
+
+// TokenStoreConfig controls where OAuth refresh tokens and the key that
+// encrypts them are kept
+type TokenStoreConfig struct {
+	Path    string `yaml:"path"`
+	KeyPath string `yaml:"key_path"`
+}
+
+// DiscoveryConfig controls how the servers of accounts that do not list them
+// are discovered
+type DiscoveryConfig struct {
+	// ISPDBPath is a local ISPDB directory or document for offline lookups
+	ISPDBPath string        `yaml:"ispdb_path"`
+	// Offline disables autoconfig, DNS and online ISPDB lookups
+	Offline   bool          `yaml:"offline"`
+	Timeout   time.Duration `yaml:"timeout"`
+	// CachePath stores discovered servers so they are looked up once
+	CachePath string        `yaml:"cache_path"`
+}
+
+// VaultConfig controls the encrypted credential vault. Its key is derived
+// from $SAPPHIREDUCK_VAULT_PASSPHRASE, or read from KeyPath when set.
+type VaultConfig struct {
+	Path    string `yaml:"path"`
+	KeyPath string `yaml:"key_path"`
+}
+
+// Identity is a send-as persona of an account. From defaults to the
+// account's username; the default identity is used when none is requested.
+type Identity struct {
+	Name          string `yaml:"name"`
+	From          string `yaml:"from"`
+	DisplayName   string `yaml:"display_name"`
+	ReplyTo       string `yaml:"reply_to"`
+	Signature     string `yaml:"signature"`
+	HTMLSignature string `yaml:"html_signature"`
+	Default       bool   `yaml:"default"`
+}
+
+// DKIMConfig enables DKIM signing of an account's outgoing mail. The
+// algorithm ("rsa" or "ed25519") is optional and checked against the key.
+type DKIMConfig struct {
+	Domain         string   `yaml:"domain"`
+	Selector       string   `yaml:"selector"`
+	PrivateKeyPath string   `yaml:"private_key_path"`
+	Algorithm      string   `yaml:"algorithm"`
+	Headers        []string `yaml:"headers"`
+}
+
+// AccountLimits caps how fast and how much an account may send and read.
+// Zero values disable the corresponding limit.
+type AccountLimits struct {
+	SendPerMinute    float64 `yaml:"send_per_minute"`
+	SendBurst        int     `yaml:"send_burst"`
+	DailySendQuota   int     `yaml:"daily_send_quota"`
+	DomainDailyQuota int     `yaml:"domain_daily_quota"`
+	ReadPerMinute    float64 `yaml:"read_per_minute"`
+	ReadBurst        int     `yaml:"read_burst"`
+}
+
+// RateLimitConfig controls where rate limit state is persisted
+type RateLimitConfig struct {
+	Path string `yaml:"path"`
+}
+
+// RecipientPolicy restricts who an account may send email to. Address and
+// domain entries may contain shell-style wildcards.
+type RecipientPolicy struct {
+	AllowedAddresses []string `yaml:"allowed_addresses"`
+	BlockedAddresses []string `yaml:"blocked_addresses"`
+	AllowedDomains   []string `yaml:"allowed_domains"`
+	BlockedDomains   []string `yaml:"blocked_domains"`
+	InternalOnly     bool     `yaml:"internal_only"`
+	InternalDomains  []string `yaml:"internal_domains"`
+	MaxRecipients    int      `yaml:"max_recipients"`
+}
+
+// OutboxConfig controls the persistent outbox used for scheduled and retried sends
+type OutboxConfig struct {
+	Path           string        `yaml:"path"`
+	MaxAttempts    int           `yaml:"max_attempts"`
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+}
+
+// ApprovalConfig controls human-in-the-loop approval of outbound actions
+type ApprovalConfig struct {
+	Mode         string        `yaml:"mode"`
+	Elicit       bool          `yaml:"elicit"`
+	Tools        []string      `yaml:"tools"`
+	TTL          time.Duration `yaml:"ttl"`
+	Path         string        `yaml:"path"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// HTTPToken is the bearer token the HTTP approve and deny endpoints
+	// require. It must differ from anything MCP clients are given, so a
+	// client cannot approve its own calls. Empty disables those endpoints.
+	HTTPToken    string        `yaml:"http_token"`
 }
 
 type EmailMessage struct {
@@ -93,10 +281,13 @@ type EmailMessage struct {
 }
 
 type SendEmailRequest struct {
-	To      string `json:"to"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
-	Account string `json:"account,omitempty"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	Account  string `json:"account,omitempty"`
+	Identity string `json:"identity,omitempty"`
+	SendAt   string `json:"send_at,omitempty"`
+	DryRun   bool   `json:"dry_run,omitempty"`
 }
 
 type ReadEmailsRequest struct {